@@ -0,0 +1,111 @@
+package gograph
+
+import "math"
+
+// EdgeAttributes describes the road an edge belongs to.
+// MaxSpeed is in km/h, zero when unknown or unlimited.
+type EdgeAttributes struct {
+	Name     string
+	Ref      string
+	Highway  string
+	Surface  string
+	Lanes    uint8
+	MaxSpeed uint16
+	Flags    AttributeFlags
+}
+
+// AttributeFlags are the yes/no attributes of a road.
+type AttributeFlags uint8
+
+const (
+	Toll AttributeFlags = 1 << iota
+	Bridge
+	Tunnel
+)
+
+// Has reports whether all the given flags are set.
+func (f AttributeFlags) Has(flags AttributeFlags) bool {
+	return f&flags == flags
+}
+
+// Attributes is a compact store of edge attributes. Each distinct set of
+// attributes is kept once in Values and the edges refer to it by index, so a
+// long street split in hundreds of edges costs a single entry. Edges holds
+// the index of each edge, by EdgeID, so parallel edges keep their own
+// attributes; the edges without attributes have noAttributes.
+type Attributes struct {
+	Values []EdgeAttributes
+	Edges  []uint32
+	// index deduplicates Values, it is rebuilt on demand after decoding.
+	index map[EdgeAttributes]uint32
+}
+
+// noAttributes is the index in Edges of the edges without attributes.
+const noAttributes = math.MaxUint32
+
+// NewAttributes creates an empty attribute store.
+func NewAttributes() *Attributes {
+	return &Attributes{
+		Values: make([]EdgeAttributes, 0),
+		Edges:  make([]uint32, 0),
+		index:  make(map[EdgeAttributes]uint32),
+	}
+}
+
+// Set assigns the attributes of an edge.
+func (a *Attributes) Set(id EdgeID, attr EdgeAttributes) {
+	if a.index == nil {
+		a.index = make(map[EdgeAttributes]uint32, len(a.Values))
+		for i, v := range a.Values {
+			a.index[v] = uint32(i)
+		}
+	}
+	for int(id) >= len(a.Edges) {
+		a.Edges = append(a.Edges, noAttributes)
+	}
+	i, ok := a.index[attr]
+	if !ok {
+		i = uint32(len(a.Values))
+		a.Values = append(a.Values, attr)
+		a.index[attr] = i
+	}
+	a.Edges[id] = i
+}
+
+// Get returns the attributes of an edge.
+func (a *Attributes) Get(id EdgeID) (EdgeAttributes, bool) {
+	if a == nil {
+		return EdgeAttributes{}, false
+	}
+	if int(id) >= len(a.Edges) || a.Edges[id] == noAttributes {
+		return EdgeAttributes{}, false
+	}
+	return a.Values[a.Edges[id]], true
+}
+
+// Len returns the number of edges with attributes.
+func (a *Attributes) Len() int {
+	if a == nil {
+		return 0
+	}
+	n := 0
+	for _, i := range a.Edges {
+		if i != noAttributes {
+			n++
+		}
+	}
+	return n
+}
+
+// unset removes the attributes of an edge.
+func (a *Attributes) unset(id EdgeID) {
+	if a != nil && int(id) < len(a.Edges) {
+		a.Edges[id] = noAttributes
+	}
+}
+
+// EdgeAttributes returns the attributes of an edge, if the graph was built
+// with an attribute store.
+func (g Graph) EdgeAttributes(id EdgeID) (EdgeAttributes, bool) {
+	return g.Attributes.Get(id)
+}
@@ -0,0 +1,35 @@
+package gograph
+
+import "testing"
+
+func TestAttributes_ParallelEdges(t *testing.T) {
+	g := testGraph()
+	g.Attributes = NewAttributes()
+	street := EdgeAttributes{Name: "Calle 26", Highway: "primary"}
+	toll := EdgeAttributes{Name: "Autopista", Highway: "motorway", Flags: Toll}
+	// a toll road lighter than the street between the nodes 1 and 2.
+	parallel := g.AddEdge(1, 2, 0.5, ClassToll)
+	for _, id := range []EdgeID{0, 2, 4} {
+		g.Attributes.Set(id, street)
+	}
+	g.Attributes.Set(parallel, toll)
+
+	if attr, _ := g.EdgeAttributes(2); attr != street {
+		t.Fatalf("expected the street kept, got %v", attr)
+	}
+	if len(g.Attributes.Values) != 2 || g.Attributes.Len() != 4 {
+		t.Fatalf("unexpected store %v", g.Attributes)
+	}
+	p := g.ShortestPath(ShortestPathCriteria{From: 0, To: 3})
+	if len(p.Segments) != 3 || p.Segments[1] != toll {
+		t.Fatalf("expected the toll road, got %v", p.Segments)
+	}
+	p = g.ShortestPath(ShortestPathCriteria{From: 0, To: 3, Avoid: ClassToll})
+	if len(p.Segments) != 3 || p.Segments[1] != street {
+		t.Fatalf("expected the street, got %v", p.Segments)
+	}
+	var empty *Attributes
+	if _, ok := empty.Get(0); ok {
+		t.Fatal("expected no attributes without a store")
+	}
+}
@@ -0,0 +1,565 @@
+package gograph
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/JesseleDuran/gograph/nearest_edge"
+	"github.com/JesseleDuran/gograph/nearest_edge/r1"
+	"github.com/JesseleDuran/gograph/nearest_edge/r2"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"unsafe"
+)
+
+// The binary format stores a graph in little-endian sections that are used in
+// place once the file is mapped in memory, so loading it does not decode the
+// edges nor rebuild the edge index. It is laid out as follows, every section
+// starting at a multiple of 8 bytes and padded with zeros:
+//
+//	header      magic "GOGRAPHB", version uint32, flags uint32, then as
+//	            uint64 the number of nodes, node data values, outgoing edges,
+//	            incoming edges, edge IDs and edge index nodes
+//	locations   S2 cell ID of each node, uint64
+//	node flags  uint8 per node, bit 0 is set for compressed nodes
+//	data        offsets uint32 (nodes+1), then the values uint64
+//	outgoing    offsets uint32 (nodes+1), then the edges
+//	incoming    offsets uint32 (nodes+1), then the edges
+//	edge ends   the from and to node IDs int32 of each edge ID, -1 when the
+//	            edge was removed
+//	elevation   float32 per node, only when the flag bit 0 is set
+//	index       the edge index nodes in preorder
+//	checksum    the CRC-32 (IEEE) of all the bytes before it, uint32, and 4
+//	            zero bytes
+//
+// An edge is 16 bytes: the node ID int32, the weight float32, the class uint8,
+// 3 zero bytes and the edge ID uint32. An edge index node is its quadrant as the float64 min x,
+// max x, min y and max y, its depth uint32, a uint32 with the bit i set when it
+// has the child i, and the number of its segments uint64, followed by the
+// segments. A segment is two points of 24 bytes: x and y float64, the node ID
+// int32 and 4 zero bytes.
+//
+// The OSM references and the edge attributes are not stored, so a binary graph
+// can not be updated with OSM change files nor have its edge attributes read.
+const (
+	binaryMagic   = "GOGRAPHB"
+	binaryVersion = 3
+	// binaryElevation is the flag of the files with the elevation section.
+	binaryElevation = 1
+	binaryHeader    = 8 + 4 + 4 + 6*8
+	edgeSize        = 16
+	pointSize       = 24
+	indexNodeSize   = 48
+)
+
+// Format is a file format for a graph.
+type Format int
+
+const (
+	// Gob is the versioned encoding/gob format written by Serialize.
+	Gob Format = iota
+	// Binary is the format that MapFile loads without decoding.
+	Binary
+)
+
+// ErrBinaryFormat is returned when a file is not a valid binary graph.
+var ErrBinaryFormat = errors.New("gograph: invalid binary graph")
+
+// SerializeAs writes the graph to a file in the given format.
+func (g Graph) SerializeAs(filePath string, format Format) error {
+	if format == Gob {
+		return g.Serialize(filePath)
+	}
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	err = g.writeBinary(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// MappedGraph is a graph whose nodes, edges and edge index are backed by a
+// file mapped in memory, shared with the other processes mapping the same
+// file. The nodes are read from the file with Node, Nodes is nil until
+// LoadNodes is called, which the functions walking all the nodes, like the
+// exports, need. The mapping is read only: changing the weights or thawing
+// the graph first copies the nodes, edges, edge ends and elevations in
+// memory, so the file is never written. The graph is frozen, see Freeze. It
+// has no OSM references nor edge attributes, see the Binary format.
+type MappedGraph struct {
+	Graph
+	data []byte
+}
+
+// MapFile maps a graph written in the Binary format. On the platforms without
+// mmap support the file is read in memory instead. Only the header and the
+// size of the sections are checked, so the pages of the file are read when
+// used; call Verify before using a file that may be corrupted, which could
+// otherwise panic.
+func MapFile(filePath string) (*MappedGraph, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	data, err := mapFile(file, int(info.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("gograph: mapping %s: %w", filePath, err)
+	}
+	g, err := decodeBinary(data)
+	if err != nil {
+		_ = unmapFile(data)
+		return nil, fmt.Errorf("gograph: %s: %w", filePath, err)
+	}
+	g.Frozen.mapped = true
+	return &MappedGraph{Graph: g, data: data}, nil
+}
+
+// Verify checks the checksum of the file, and that the offsets of its sections
+// and the node IDs of its edges, edge ends and edge index are in range. It
+// reads the whole file and returns an error wrapping ErrBinaryFormat when it
+// is corrupted.
+func (m *MappedGraph) Verify() error {
+	g, err := decodeBinary(m.data)
+	if err != nil {
+		return err
+	}
+	return verifyBinary(g, m.data)
+}
+
+// LoadNodes fills Nodes from the file, the node data is kept in place.
+func (m *MappedGraph) LoadNodes() {
+	if m.Nodes == nil && m.Frozen != nil && m.Frozen.nodes != nil {
+		m.Nodes = m.Frozen.nodes.list()
+	}
+}
+
+// Close releases the mapping. The graph must not be used afterwards, thaw it
+// before closing to keep using it.
+func (m *MappedGraph) Close() error {
+	if m.data == nil {
+		return nil
+	}
+	err := unmapFile(m.data)
+	m.data = nil
+	return err
+}
+
+// binaryWriter writes little-endian values, remembering the first error, and
+// computes the checksum of the bytes written.
+type binaryWriter struct {
+	w   io.Writer
+	crc hash.Hash32
+	buf [8]byte
+	n   int
+	err error
+}
+
+func (w *binaryWriter) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(b)
+	w.crc.Write(b[:n])
+	w.n += n
+	w.err = err
+}
+
+func (w *binaryWriter) u8(v uint8) {
+	w.buf[0] = v
+	w.write(w.buf[:1])
+}
+
+func (w *binaryWriter) u32(v uint32) {
+	binary.LittleEndian.PutUint32(w.buf[:], v)
+	w.write(w.buf[:4])
+}
+
+func (w *binaryWriter) u64(v uint64) {
+	binary.LittleEndian.PutUint64(w.buf[:], v)
+	w.write(w.buf[:8])
+}
+
+func (w *binaryWriter) f64(v float64) {
+	w.u64(math.Float64bits(v))
+}
+
+// pad aligns the next section to 8 bytes.
+func (w *binaryWriter) pad() {
+	for w.n%8 != 0 {
+		w.u8(0)
+	}
+}
+
+func (w *binaryWriter) csr(c CSR) {
+	for _, o := range c.Offsets {
+		w.u32(o)
+	}
+	w.pad()
+	for _, e := range c.Edges {
+		w.u32(uint32(e.ID))
+		w.u32(math.Float32bits(e.Weight))
+		w.u32(uint32(e.Class))
+		w.u32(uint32(e.EdgeID))
+	}
+	w.pad()
+}
+
+func (w *binaryWriter) point(p r2.Point) {
+	w.f64(p.X)
+	w.f64(p.Y)
+	w.u32(uint32(p.ID))
+	w.u32(0)
+}
+
+func (w *binaryWriter) index(n *nearest_edge.Node) {
+	mask := uint32(0)
+	for i, c := range n.Children {
+		if c != nil {
+			mask |= 1 << i
+		}
+	}
+	w.f64(n.Quadrant.X.Min)
+	w.f64(n.Quadrant.X.Max)
+	w.f64(n.Quadrant.Y.Min)
+	w.f64(n.Quadrant.Y.Max)
+	w.u32(uint32(n.Depth))
+	w.u32(mask)
+	w.u64(uint64(len(n.Segments)))
+	for _, s := range n.Segments {
+		w.point(s.A)
+		w.point(s.B)
+	}
+	for _, c := range n.Children {
+		if c != nil {
+			w.index(c)
+		}
+	}
+}
+
+func (g Graph) writeBinary(out io.Writer) error {
+	frozen := g.Frozen
+	if frozen == nil {
+		frozen = &Frozen{Outgoing: NewCSR(g.OutgoingEdges), Incoming: NewCSR(g.IncomingEdges)}
+	}
+	index := g.EdgeIndex
+	if len(index.Segments) == 0 && index.Children == [4]*nearest_edge.Node{} {
+		index = g.BuildEdgeIndex()
+	}
+	values := 0
+	for _, n := range g.Nodes {
+		values += len(n.Data)
+	}
+	flags := uint32(0)
+	if len(g.Elevation) == len(g.Nodes) && g.Elevation != nil {
+		flags |= binaryElevation
+	}
+
+	w := &binaryWriter{w: out, crc: crc32.NewIEEE()}
+	w.write([]byte(binaryMagic))
+	w.u32(binaryVersion)
+	w.u32(flags)
+	for _, count := range []int{len(g.Nodes), values, len(frozen.Outgoing.Edges), len(frozen.Incoming.Edges), len(g.EdgeEnds), countIndexNodes(&index)} {
+		w.u64(uint64(count))
+	}
+	for _, n := range g.Nodes {
+		w.u64(n.Location)
+	}
+	for _, n := range g.Nodes {
+		if n.Compressed {
+			w.u8(1)
+		} else {
+			w.u8(0)
+		}
+	}
+	w.pad()
+	offset := uint32(0)
+	w.u32(offset)
+	for _, n := range g.Nodes {
+		offset += uint32(len(n.Data))
+		w.u32(offset)
+	}
+	w.pad()
+	for _, n := range g.Nodes {
+		for _, v := range n.Data {
+			w.u64(v)
+		}
+	}
+	w.csr(frozen.Outgoing)
+	w.csr(frozen.Incoming)
+	for _, ends := range g.EdgeEnds {
+		w.u32(uint32(ends.From))
+		w.u32(uint32(ends.To))
+	}
+	if flags&binaryElevation != 0 {
+		for _, e := range g.Elevation {
+			w.u32(math.Float32bits(e))
+		}
+		w.pad()
+	}
+	w.index(&index)
+	w.u32(w.crc.Sum32())
+	w.u32(0)
+	return w.err
+}
+
+func countIndexNodes(n *nearest_edge.Node) int {
+	count := 1
+	for _, c := range n.Children {
+		if c != nil {
+			count += countIndexNodes(c)
+		}
+	}
+	return count
+}
+
+// binaryReader hands out the sections of a binary graph.
+type binaryReader struct {
+	data []byte
+	at   int
+	err  error
+}
+
+// section returns the next size bytes and skips the padding after them.
+func (r *binaryReader) section(size int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if size < 0 || r.at+size > len(r.data) {
+		r.err = fmt.Errorf("%w: truncated at byte %d", ErrBinaryFormat, r.at)
+		return nil
+	}
+	b := r.data[r.at : r.at+size : r.at+size]
+	r.at += (size + 7) &^ 7
+	return b
+}
+
+// The sections are used in place, which requires a little-endian host and
+// the Go layout of the types to match the file.
+func checkBinaryLayout() error {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) != 1 {
+		return errors.New("gograph: binary graphs can only be read on little-endian hosts")
+	}
+	if unsafe.Sizeof(Edge{}) != edgeSize || unsafe.Offsetof(Edge{}.Weight) != 4 || unsafe.Offsetof(Edge{}.Class) != 8 || unsafe.Offsetof(Edge{}.EdgeID) != 12 || unsafe.Sizeof(EdgeKey{}) != 8 ||
+		unsafe.Sizeof(r2.Point{}) != pointSize || unsafe.Offsetof(r2.Point{}.ID) != 16 || unsafe.Sizeof(r2.Segment{}) != 2*pointSize {
+		return errors.New("gograph: unexpected memory layout for binary graphs")
+	}
+	return nil
+}
+
+// cast reinterprets the bytes of a section as a slice of T.
+func cast[T any](b []byte) []T {
+	var zero T
+	size := int(unsafe.Sizeof(zero))
+	if len(b) < size {
+		return nil
+	}
+	return unsafe.Slice((*T)(unsafe.Pointer(&b[0])), len(b)/size)
+}
+
+// mappedNodes are the node sections of a binary graph, used in place.
+type mappedNodes struct {
+	locations []uint64
+	flags     []byte
+	offsets   []uint32
+	values    []uint64
+}
+
+func (m *mappedNodes) node(id int32) Node {
+	n := Node{ID: id, Location: m.locations[id], Compressed: m.flags[id]&1 != 0}
+	if start, end := m.offsets[id], m.offsets[id+1]; end > start {
+		n.Data = m.values[start:end:end]
+	}
+	return n
+}
+
+func (m *mappedNodes) list() []Node {
+	nodes := make([]Node, len(m.locations))
+	for i := range nodes {
+		nodes[i] = m.node(int32(i))
+	}
+	return nodes
+}
+
+// decodeBinary builds a graph on the data of a binary file. The sections are
+// used in place and the nodes are read from them with Node, only the edge
+// index tree is allocated. Only the header and the size of the sections are
+// checked, see verifyBinary.
+func decodeBinary(data []byte) (Graph, error) {
+	if err := checkBinaryLayout(); err != nil {
+		return Graph{}, err
+	}
+	if len(data) < binaryHeader || string(data[:8]) != binaryMagic {
+		return Graph{}, ErrBinaryFormat
+	}
+	le := binary.LittleEndian
+	if v := le.Uint32(data[8:]); v != binaryVersion {
+		return Graph{}, fmt.Errorf("%w: unsupported version %d", ErrBinaryFormat, v)
+	}
+	if len(data) < binaryHeader+8 {
+		return Graph{}, fmt.Errorf("%w: truncated", ErrBinaryFormat)
+	}
+	data = data[:len(data)-8]
+	flags := le.Uint32(data[12:])
+	counts := make([]int, 6)
+	for i := range counts {
+		c := le.Uint64(data[16+i*8:])
+		if c > uint64(len(data)) {
+			return Graph{}, fmt.Errorf("%w: truncated", ErrBinaryFormat)
+		}
+		counts[i] = int(c)
+	}
+	nodes, values, outgoing, incoming, edgeIDs, indexNodes := counts[0], counts[1], counts[2], counts[3], counts[4], counts[5]
+
+	r := &binaryReader{data: data, at: binaryHeader}
+	mapped := &mappedNodes{
+		locations: cast[uint64](r.section(nodes * 8)),
+		flags:     r.section(nodes),
+		offsets:   cast[uint32](r.section((nodes + 1) * 4)),
+		values:    cast[uint64](r.section(values * 8)),
+	}
+	out := CSR{Offsets: cast[uint32](r.section((nodes + 1) * 4)), Edges: cast[Edge](r.section(outgoing * edgeSize))}
+	in := CSR{Offsets: cast[uint32](r.section((nodes + 1) * 4)), Edges: cast[Edge](r.section(incoming * edgeSize))}
+	ends := cast[EdgeKey](r.section(edgeIDs * 8))
+	var elevation []float32
+	if flags&binaryElevation != 0 {
+		elevation = cast[float32](r.section(nodes * 4))
+	}
+	read := 0
+	root := r.index(&read)
+	if r.err == nil && read != indexNodes {
+		r.err = fmt.Errorf("%w: expected %d index nodes, read %d", ErrBinaryFormat, indexNodes, read)
+	}
+	if r.err == nil && r.at != len(data) {
+		r.err = fmt.Errorf("%w: %d bytes after the edge index", ErrBinaryFormat, len(data)-r.at)
+	}
+	if r.err != nil {
+		return Graph{}, r.err
+	}
+	return Graph{
+		Frozen:    &Frozen{Outgoing: out, Incoming: in, nodes: mapped},
+		Elevation: elevation,
+		EdgeEnds:  ends,
+		EdgeIndex: *root,
+	}, nil
+}
+
+// verifyBinary checks a graph decoded from data, see MappedGraph.Verify.
+func verifyBinary(g Graph, data []byte) error {
+	end := len(data) - 8
+	if crc32.ChecksumIEEE(data[:end]) != binary.LittleEndian.Uint32(data[end:]) {
+		return fmt.Errorf("%w: checksum mismatch", ErrBinaryFormat)
+	}
+	nodes := g.Frozen.nodes
+	out, in := g.Frozen.Outgoing, g.Frozen.Incoming
+	if !validOffsets(nodes.offsets, len(nodes.values)) || !validOffsets(out.Offsets, len(out.Edges)) || !validOffsets(in.Offsets, len(in.Edges)) {
+		return fmt.Errorf("%w: inconsistent offsets", ErrBinaryFormat)
+	}
+	count := len(nodes.locations)
+	for _, edges := range [2][]Edge{out.Edges, in.Edges} {
+		for _, e := range edges {
+			if e.ID < 0 || int(e.ID) >= count {
+				return fmt.Errorf("%w: edge to node %d out of range", ErrBinaryFormat, e.ID)
+			}
+		}
+	}
+	for id, key := range g.EdgeEnds {
+		if key != removedEdge && (key.From < 0 || int(key.From) >= count || key.To < 0 || int(key.To) >= count) {
+			return fmt.Errorf("%w: ends of edge %d out of range", ErrBinaryFormat, id)
+		}
+	}
+	if !validIndex(&g.EdgeIndex, count) {
+		return fmt.Errorf("%w: edge index segment out of range", ErrBinaryFormat)
+	}
+	return nil
+}
+
+// readBinary decodes and verifies a binary graph read in memory, with its
+// nodes loaded.
+func readBinary(data []byte) (Graph, error) {
+	g, err := decodeBinary(data)
+	if err != nil {
+		return Graph{}, err
+	}
+	if err := verifyBinary(g, data); err != nil {
+		return Graph{}, err
+	}
+	g.Nodes = g.Frozen.nodes.list()
+	g.Frozen.nodes = nil
+	return g, nil
+}
+
+// validOffsets reports whether the offsets of a section start at 0, never
+// decrease and end at its number of values.
+func validOffsets(offsets []uint32, values int) bool {
+	if offsets[0] != 0 || offsets[len(offsets)-1] != uint32(values) {
+		return false
+	}
+	for i := 1; i < len(offsets); i++ {
+		if offsets[i] < offsets[i-1] {
+			return false
+		}
+	}
+	return true
+}
+
+// index reads an edge index node and its children.
+func (r *binaryReader) index(read *int) *nearest_edge.Node {
+	b := r.section(indexNodeSize)
+	if r.err != nil {
+		return nil
+	}
+	*read++
+	le := binary.LittleEndian
+	f := func(i int) float64 { return math.Float64frombits(le.Uint64(b[i*8:])) }
+	n := &nearest_edge.Node{
+		Quadrant: r2.Rect{X: r1.Interval{Min: f(0), Max: f(1)}, Y: r1.Interval{Min: f(2), Max: f(3)}},
+		Depth:    int(le.Uint32(b[32:])),
+	}
+	mask := le.Uint32(b[36:])
+	if segments := le.Uint64(b[40:]); segments > 0 {
+		if segments > uint64(len(r.data)) {
+			r.err = fmt.Errorf("%w: truncated", ErrBinaryFormat)
+			return nil
+		}
+		n.Segments = cast[r2.Segment](r.section(int(segments) * 2 * pointSize))
+	}
+	for i := range n.Children {
+		if mask&(1<<i) != 0 {
+			n.Children[i] = r.index(read)
+		}
+	}
+	return n
+}
+
+// validIndex reports whether the segments of an edge index node and its
+// children relate the given number of nodes.
+func validIndex(n *nearest_edge.Node, nodes int) bool {
+	for _, s := range n.Segments {
+		if s.A.ID < 0 || int(s.A.ID) >= nodes || s.B.ID < 0 || int(s.B.ID) >= nodes {
+			return false
+		}
+	}
+	for _, c := range n.Children {
+		if c != nil && !validIndex(c, nodes) {
+			return false
+		}
+	}
+	return true
+}
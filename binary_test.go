@@ -0,0 +1,153 @@
+package gograph
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMapFile(t *testing.T) {
+	g := testGraph()
+	g.Nodes[1].Data = []uint64{7, 8}
+	g.Elevation = []float32{1, 2, 3, 4}
+	g.Freeze()
+	path := filepath.Join(t.TempDir(), "graph.bin")
+	if err := g.SerializeAs(path, Binary); err != nil {
+		t.Fatal(err)
+	}
+	m, err := MapFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	// the nodes are read from the file until loaded.
+	if !m.IsFrozen() || m.Nodes != nil || !reflect.DeepEqual(m.Elevation, g.Elevation) {
+		t.Fatalf("unexpected nodes %v", m.Nodes)
+	}
+	for id := range g.Nodes {
+		if n := m.Node(int32(id)); !reflect.DeepEqual(n, g.Nodes[id]) {
+			t.Fatalf("unexpected node %v", n)
+		}
+		if !reflect.DeepEqual(m.Outgoing(int32(id)), g.Outgoing(int32(id))) || !reflect.DeepEqual(m.Incoming(int32(id)), g.Incoming(int32(id))) {
+			t.Fatalf("unexpected edges of node %d", id)
+		}
+	}
+	if !reflect.DeepEqual(m.EdgeIndex, g.EdgeIndex) {
+		t.Fatal("expected the edge index to be kept")
+	}
+	p := m.ShortestPath(ShortestPathCriteria{From: 0, To: 3})
+	if p.Cost != 3 || !reflect.DeepEqual(p.Data, []uint64{7, 8}) {
+		t.Fatalf("unexpected path %v", p)
+	}
+	at := Coordinate{Lat: 4.6001, Lng: -74.0789}
+	want, _ := g.ProjectCoordinate(at)
+	if id, _ := m.ProjectCoordinate(at); id != want {
+		t.Fatalf("expected to project on node %d, got %d", want, id)
+	}
+	if m.LoadNodes(); !reflect.DeepEqual(m.Nodes, g.Nodes) {
+		t.Fatalf("unexpected nodes loaded %v", m.Nodes)
+	}
+	// the mapping is read only, reweighting copies the edges and leaves the
+	// file as it was.
+	m.Reweight(func(from, to int32, weight float32) float32 { return weight * 2 })
+	if cost := m.Dijkstra(ShortestPathCriteria{From: 0, To: 3}); cost != 6 {
+		t.Fatalf("expected the mapped graph reweighted, got %f", cost)
+	}
+	again, err := MapFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost := again.Dijkstra(ShortestPathCriteria{From: 0, To: 3}); cost != 3 {
+		t.Fatalf("expected the file unchanged, got %f", cost)
+	}
+	if !again.RemoveEdgeByID(5) || again.Edges() != g.Edges()-2 || !reflect.DeepEqual(again.Nodes, g.Nodes) {
+		t.Fatal("expected to remove an edge of the mapped graph")
+	}
+	again.Close()
+	if d := Deserialize(path); !reflect.DeepEqual(d.Nodes, g.Nodes) || d.Edges() != g.Edges() {
+		t.Fatal("expected Deserialize to read the binary format")
+	}
+
+	data, _ := os.ReadFile(path)
+	data[8] = 9
+	_ = os.WriteFile(path, data, 0o644)
+	if _, err := MapFile(path); !errors.Is(err, ErrBinaryFormat) {
+		t.Fatalf("expected a version error, got %v", err)
+	}
+}
+
+// corrupt writes the binary graph changed by fn to a new file, with a valid
+// checksum when fix is set.
+func corrupt(t *testing.T, data []byte, fix bool, fn func([]byte)) string {
+	data = append([]byte(nil), data...)
+	fn(data)
+	if fix {
+		end := len(data) - 8
+		binary.LittleEndian.PutUint32(data[end:], crc32.ChecksumIEEE(data[:end]))
+	}
+	path := filepath.Join(t.TempDir(), "graph.bin")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMapFile_Corrupted(t *testing.T) {
+	g := testGraph()
+	g.Freeze()
+	var buf bytes.Buffer
+	if err := g.writeBinary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	nodes := len(g.Nodes)
+	// the sections after the header, see the Binary format.
+	locations := binaryHeader
+	dataOffsets := locations + nodes*8 + (nodes+7)&^7
+	outOffsets := dataOffsets + ((nodes+1)*4+7)&^7
+	outEdges := outOffsets + ((nodes+1)*4+7)&^7
+
+	tests := map[string]string{
+		"checksum": corrupt(t, data, false, func(b []byte) { b[locations]++ }),
+		"truncated": corrupt(t, data, true, func(b []byte) {
+			binary.LittleEndian.PutUint64(b[16+2*8:], uint64(len(b)))
+		}),
+		"decreasing offsets": corrupt(t, data, true, func(b []byte) {
+			binary.LittleEndian.PutUint32(b[outOffsets+4:], 5)
+		}),
+		"edge out of range": corrupt(t, data, true, func(b []byte) {
+			binary.LittleEndian.PutUint32(b[outEdges:], uint32(nodes))
+		}),
+		"negative edge": corrupt(t, data, true, func(b []byte) {
+			binary.LittleEndian.PutUint32(b[outEdges:], math.MaxUint32)
+		}),
+	}
+	// only the header and the size of the sections are checked when mapping.
+	m, err := MapFile(tests["checksum"])
+	if err != nil {
+		t.Fatalf("expected the checksum not checked, got %v", err)
+	}
+	m.Close()
+	for name, path := range tests {
+		m, err := MapFile(path)
+		if err == nil {
+			err = m.Verify()
+			m.Close()
+		}
+		if !errors.Is(err, ErrBinaryFormat) {
+			t.Fatalf("%s: expected ErrBinaryFormat, got %v", name, err)
+		}
+		if _, err := ReadFile(path); !errors.Is(err, ErrBinaryFormat) {
+			t.Fatalf("%s: expected ReadFile to fail with ErrBinaryFormat, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Command tiledump writes the edges of a graph file as Mapbox Vector Tiles in
+// a z/x/y.mvt directory tree, to be served by any static file server to a
+// web map. The edges are not simplified, so the zooms start at mvt.MinZoom.
+//
+//	tiledump -graph bogota.graph -out tiles -minzoom 12 -maxzoom 16
+package main
+
+import (
+	"flag"
+	"fmt"
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/JesseleDuran/gograph/mvt"
+	"github.com/golang/geo/s2"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+func main() {
+	graphPath := flag.String("graph", "", "graph file written by Serialize or SerializeAs")
+	out := flag.String("out", "tiles", "directory of the tiles")
+	minZoom := flag.Int("minzoom", mvt.MinZoom, fmt.Sprintf("lowest zoom written, at least %d", mvt.MinZoom))
+	maxZoom := flag.Int("maxzoom", 16, "highest zoom written")
+	layer := flag.String("layer", mvt.DefaultLayer, "name of the layer of the edges")
+	flag.Parse()
+	if *graphPath == "" || *minZoom > *maxZoom {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *minZoom < mvt.MinZoom {
+		fmt.Fprintf(os.Stderr, "the lowest zoom is %d, the edges are not simplified for lower zooms\n", mvt.MinZoom)
+		os.Exit(2)
+	}
+
+	g, err := graph.ReadFile(*graphPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(g.Nodes) == 0 {
+		log.Fatalf("%s has no nodes", *graphPath)
+	}
+	bounds := s2.EmptyRect()
+	for _, n := range g.Nodes {
+		bounds = bounds.AddPoint(s2.CellID(n.Location).LatLng())
+	}
+
+	written := 0
+	for z := *minZoom; z <= *maxZoom; z++ {
+		minX, minY := mvt.TileOf(bounds.Hi().Lat.Degrees(), bounds.Lo().Lng.Degrees(), z)
+		maxX, maxY := mvt.TileOf(bounds.Lo().Lat.Degrees(), bounds.Hi().Lng.Degrees(), z)
+		for x := minX; x <= maxX; x++ {
+			for y := minY; y <= maxY; y++ {
+				tile := mvt.Encode(g, z, x, y, mvt.Options{Layer: *layer})
+				if tile == nil {
+					continue
+				}
+				dir := filepath.Join(*out, strconv.Itoa(z), strconv.Itoa(x))
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					log.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.mvt", y)), tile, 0o644); err != nil {
+					log.Fatal(err)
+				}
+				written++
+			}
+		}
+	}
+	log.Printf("wrote %d tiles of zoom %d to %d in %s", written, *minZoom, *maxZoom, *out)
+}
@@ -0,0 +1,119 @@
+package gograph
+
+import "sort"
+
+// Components computes the strongly connected components of the graph, the
+// groups of nodes that can all be reached from each other. It returns the
+// component of each node, indexed by node ID, and the number of components.
+// Components are numbered by decreasing size, so the main component is 0.
+func (g Graph) Components() ([]int32, int) {
+	const unvisited = -1
+	n := len(g.Nodes)
+	index := make([]int32, n)
+	low := make([]int32, n)
+	onStack := make([]bool, n)
+	component := make([]int32, n)
+	for i := range index {
+		index[i] = unvisited
+	}
+	stack := make([]int32, 0)
+	sizes := make([]int, 0)
+	next := int32(0)
+
+	// frame is a node being visited and the position of the next outgoing
+	// edge to follow, the search is iterative to support long roads.
+	type frame struct {
+		node int32
+		edge int
+	}
+	for root := range g.Nodes {
+		if index[root] != unvisited {
+			continue
+		}
+		calls := []frame{{node: int32(root)}}
+		index[root], low[root] = next, next
+		next++
+		stack = append(stack, int32(root))
+		onStack[root] = true
+		for len(calls) > 0 {
+			f := &calls[len(calls)-1]
+			v := f.node
+			if edges := g.Outgoing(v); f.edge < len(edges) {
+				w := edges[f.edge].ID
+				f.edge++
+				if index[w] == unvisited {
+					index[w], low[w] = next, next
+					next++
+					stack = append(stack, w)
+					onStack[w] = true
+					calls = append(calls, frame{node: w})
+				} else if onStack[w] && index[w] < low[v] {
+					low[v] = index[w]
+				}
+				continue
+			}
+			calls = calls[:len(calls)-1]
+			if len(calls) > 0 {
+				parent := calls[len(calls)-1].node
+				if low[v] < low[parent] {
+					low[parent] = low[v]
+				}
+			}
+			if low[v] != index[v] {
+				continue
+			}
+			c := int32(len(sizes))
+			size := 0
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component[w] = c
+				size++
+				if w == v {
+					break
+				}
+			}
+			sizes = append(sizes, size)
+		}
+	}
+
+	// number the components by decreasing size.
+	order := make([]int32, len(sizes))
+	for i := range order {
+		order[i] = int32(i)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return sizes[order[i]] > sizes[order[j]]
+	})
+	rank := make([]int32, len(sizes))
+	for i, c := range order {
+		rank[c] = int32(i)
+	}
+	for i, c := range component {
+		component[i] = rank[c]
+	}
+	return component, len(sizes)
+}
+
+// KeepLargestComponent removes the nodes outside the main strongly connected
+// component, like the islands left by clipping the roads at the coverage
+// border, so a search can not snap to a node it can not leave. The nodes are
+// renumbered, see Renumber. It returns the number of nodes removed.
+func (g *Graph) KeepLargestComponent() int {
+	component, count := g.Components()
+	if count <= 1 {
+		return 0
+	}
+	ids := make([]int32, len(component))
+	next := int32(0)
+	for i, c := range component {
+		ids[i] = -1
+		if c == 0 {
+			ids[i] = next
+			next++
+		}
+	}
+	g.Renumber(ids)
+	return len(ids) - int(next)
+}
@@ -0,0 +1,48 @@
+package gograph
+
+import (
+	"github.com/golang/geo/s2"
+	"testing"
+)
+
+func TestGraph_KeepLargestComponent(t *testing.T) {
+	g := testGraph()
+	// an island of two nodes, and a node that can be reached but not left.
+	for i := 0; i < 3; i++ {
+		g.AddNode(Node{Location: uint64(s2.CellIDFromLatLng(s2.LatLngFromDegrees(4.61, -74.08+float64(i)*0.001)))})
+	}
+	g.RelateNodes(g.Nodes[4], g.Nodes[5], 1, Bidirectional)
+	g.RelateNodes(g.Nodes[1], g.Nodes[6], 1, LeftToRight)
+	g.SetOSMNode(5, 500)
+	g.SetOSMNode(2, 200)
+	g.Elevation = []float32{0, 1, 2, 3, 4, 5, 6}
+	g.Attributes = NewAttributes()
+	g.Attributes.Set(2, EdgeAttributes{Name: "Calle 26"})
+	g.Attributes.Set(6, EdgeAttributes{Name: "Island"})
+
+	component, count := g.Components()
+	if count != 4 || component[0] != 0 || component[1] != 0 || component[2] != 0 || component[4] != 1 {
+		t.Fatalf("unexpected components %v %d", component, count)
+	}
+	if removed := g.KeepLargestComponent(); removed != 4 {
+		t.Fatalf("expected 4 nodes removed, got %d", removed)
+	}
+	if len(g.Nodes) != 3 || g.Nodes[2].ID != 2 || len(g.OutgoingEdges[1]) != 2 || len(g.IncomingEdges[2]) != 1 {
+		t.Fatalf("unexpected graph %v %v", g.Nodes, g.OutgoingEdges)
+	}
+	if id, ok := g.NodeByOSMID(200); !ok || id != 2 {
+		t.Fatalf("expected the OSM node kept, got %d %v", id, ok)
+	}
+	if _, ok := g.NodeByOSMID(500); ok {
+		t.Fatal("expected the OSM node of the island removed")
+	}
+	if _, ok := g.EdgeAttributes(2); !ok || g.Attributes.Len() != 1 {
+		t.Fatalf("unexpected attributes %v", g.Attributes.Edges)
+	}
+	if len(g.Elevation) != 3 || g.Elevation[2] != 2 {
+		t.Fatalf("unexpected elevation %v", g.Elevation)
+	}
+	if p := g.ShortestPath(ShortestPathCriteria{From: 0, To: 2}); p.Cost != 2 {
+		t.Fatalf("expected cost 2, got %f", p.Cost)
+	}
+}
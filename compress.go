@@ -11,6 +11,7 @@ import (
 // C is conflict factor threshold, which controls the behavior of
 // the technique and trades the compression ratio for the map-matching quality.
 func (g *Graph) Compress(C float64) {
+	g.Thaw()
 	compressedNodes := 0
 	originalNodes := len(g.Nodes)
 	for _, n := range g.Nodes {
@@ -124,7 +125,7 @@ func (g *Graph) DeleteAndMerge(n Node) {
 	for _, eId := range g.IncomingEdges[n.ID] {
 		for _, eOut := range g.OutgoingEdges[n.ID] {
 			w := eId.Weight + eOut.Weight
-			g.RelateNodes(g.Nodes[eId.ID], g.Nodes[eOut.ID], w, LeftToRight)
+			g.RelateNodesClass(g.Nodes[eId.ID], g.Nodes[eOut.ID], w, LeftToRight, eId.Class|eOut.Class)
 		}
 	}
 	g.NodeAsCompressed(n.ID)
@@ -0,0 +1,144 @@
+package gograph
+
+import "sort"
+
+// CSR stores the edges of all the nodes in a single array, in compressed
+// sparse row form. The edges of the node id are Edges[Offsets[id]:Offsets[id+1]].
+type CSR struct {
+	Offsets []uint32
+	Edges   []Edge
+}
+
+// Frozen is the compact, read only form of the relations of a graph.
+type Frozen struct {
+	Outgoing CSR
+	Incoming CSR
+	// mapped is set when the edges, and the other slices of the graph that
+	// MapFile places on the file, are read only.
+	mapped bool
+	// nodes are the node sections of a graph mapped by MapFile, read by Node
+	// until Nodes is loaded.
+	nodes *mappedNodes
+}
+
+// NewCSR packs the relations into a CSR. The edges of each node are sorted by
+// the node they relate, then by weight and EdgeID. Parallel edges are all
+// kept, as they may differ in class or direction and their IDs must stay
+// valid.
+func NewCSR(r Relations) CSR {
+	total := 0
+	for _, edges := range r {
+		total += len(edges)
+	}
+	c := CSR{Offsets: make([]uint32, len(r)+1), Edges: make([]Edge, 0, total)}
+	for id, edges := range r {
+		start := len(c.Edges)
+		c.Edges = append(c.Edges, edges...)
+		node := c.Edges[start:]
+		sort.Slice(node, func(i, j int) bool {
+			if node[i].ID != node[j].ID {
+				return node[i].ID < node[j].ID
+			}
+			if node[i].Weight != node[j].Weight {
+				return node[i].Weight < node[j].Weight
+			}
+			return node[i].EdgeID < node[j].EdgeID
+		})
+		c.Offsets[id+1] = uint32(len(c.Edges))
+	}
+	return c
+}
+
+// Of returns the edges of a node.
+func (c CSR) Of(id int32) []Edge {
+	start, end := c.Offsets[id], c.Offsets[id+1]
+	return c.Edges[start:end:end]
+}
+
+// Relations unpacks the CSR.
+func (c CSR) Relations() Relations {
+	r := make(Relations, len(c.Offsets)-1)
+	for id := range r {
+		r[id] = append(make([]Edge, 0), c.Of(int32(id))...)
+	}
+	return r
+}
+
+// Freeze converts the relations of the graph to the frozen form, which uses a
+// fraction of their memory and is faster to search. The edges and their IDs
+// are kept as they are, see NewCSR. A frozen graph can still be reweighted, but adding or
+// removing nodes and edges thaws it first.
+func (g *Graph) Freeze() {
+	if g.Frozen != nil {
+		return
+	}
+	g.Frozen = &Frozen{
+		Outgoing: NewCSR(g.OutgoingEdges),
+		Incoming: NewCSR(g.IncomingEdges),
+	}
+	g.OutgoingEdges, g.IncomingEdges = nil, nil
+}
+
+// Thaw converts a frozen graph back to mutable relations.
+func (g *Graph) Thaw() {
+	if g.Frozen == nil {
+		return
+	}
+	g.detach()
+	g.OutgoingEdges = g.Frozen.Outgoing.Relations()
+	g.IncomingEdges = g.Frozen.Incoming.Relations()
+	g.Frozen = nil
+}
+
+// detach copies in memory the slices of a graph mapped by MapFile, which are
+// read only, so they can be changed. The other copies of the graph keep using
+// the mapping.
+func (g *Graph) detach() {
+	if g.Frozen == nil || !g.Frozen.mapped {
+		return
+	}
+	if g.Nodes == nil && g.Frozen.nodes != nil {
+		g.Nodes = g.Frozen.nodes.list()
+	}
+	g.Frozen = &Frozen{
+		Outgoing: CSR{Offsets: g.Frozen.Outgoing.Offsets, Edges: append([]Edge(nil), g.Frozen.Outgoing.Edges...)},
+		Incoming: CSR{Offsets: g.Frozen.Incoming.Offsets, Edges: append([]Edge(nil), g.Frozen.Incoming.Edges...)},
+	}
+	g.EdgeEnds = append([]EdgeKey(nil), g.EdgeEnds...)
+	if g.Elevation != nil {
+		g.Elevation = append([]float32(nil), g.Elevation...)
+	}
+	nodes := make([]Node, len(g.Nodes))
+	for i, n := range g.Nodes {
+		if n.Data != nil {
+			n.Data = append([]uint64(nil), n.Data...)
+		}
+		nodes[i] = n
+	}
+	g.Nodes = nodes
+}
+
+// IsFrozen reports whether the graph is in the frozen form.
+func (g Graph) IsFrozen() bool {
+	return g.Frozen != nil
+}
+
+// Outgoing returns the edges leaving a node, whether the graph is frozen or
+// not. The weights of the edges can be changed, except on a graph mapped by
+// MapFile where SetEdgeWeightByID is needed, but the slice must not grow.
+func (g *Graph) Outgoing(id int32) []Edge {
+	if g.Frozen != nil {
+		return g.Frozen.Outgoing.Of(id)
+	}
+	return g.OutgoingEdges[id]
+}
+
+// Incoming returns the edges entering a node, whether the graph is frozen or
+// not. The weights of the edges can be changed, except on a graph mapped by
+// MapFile where SetEdgeWeightByID is needed, but the slice must not grow.
+func (g *Graph) Incoming(id int32) []Edge {
+	if g.Frozen != nil {
+		return g.Frozen.Incoming.Of(id)
+	}
+	return g.IncomingEdges[id]
+}
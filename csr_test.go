@@ -0,0 +1,50 @@
+package gograph
+
+import "testing"
+
+func TestGraph_Freeze(t *testing.T) {
+	g := testGraph()
+	// a parallel edge, heavier than the first one.
+	g.RelateNodes(g.Nodes[0], g.Nodes[1], 3, LeftToRight)
+	edges := g.Edges()
+	g.Freeze()
+	if !g.IsFrozen() || g.OutgoingEdges != nil {
+		t.Fatal("expected the relations to be frozen")
+	}
+	if g.Edges() != edges || len(g.Outgoing(0)) != 3 || g.Outgoing(0)[0].Weight != 1 || g.Outgoing(0)[1].Weight != 3 {
+		t.Fatalf("expected the parallel edges kept and sorted, got %v", g.Outgoing(0))
+	}
+	if _, ok := g.EdgeByID(6); !ok {
+		t.Fatal("expected the ID of the parallel edge kept")
+	}
+	if p := g.ShortestPath(ShortestPathCriteria{From: 0, To: 3}); p.Cost != 3 || len(p.Nodes) != 4 {
+		t.Fatalf("unexpected path %v", p)
+	}
+	g.Reweight(func(from, to int32, weight float32) float32 { return weight * 2 })
+	if cost := g.Dijkstra(ShortestPathCriteria{From: 0, To: 3}); cost != 6 {
+		t.Fatalf("expected the frozen graph reweighted, got %f", cost)
+	}
+	if g.Incoming(3)[1].Weight != 2 {
+		t.Fatalf("expected the incoming edges reweighted, got %v", g.Incoming(3))
+	}
+	g.RelateNodes(g.Nodes[3], g.Nodes[0], 1, LeftToRight)
+	if g.IsFrozen() || len(g.OutgoingEdges[3]) != 1 || len(g.IncomingEdges[0]) != 2 {
+		t.Fatalf("expected the graph thawed to relate the nodes, got %v", g.OutgoingEdges)
+	}
+}
+
+func TestGraph_Freeze_ParallelClasses(t *testing.T) {
+	g := Graph{}
+	for i := 0; i < 2; i++ {
+		g.AddNode(Node{})
+	}
+	g.RelateNodesClass(g.Nodes[0], g.Nodes[1], 1, LeftToRight, ClassToll)
+	g.RelateNodes(g.Nodes[0], g.Nodes[1], 4, LeftToRight)
+	g.Freeze()
+	if cost := g.Dijkstra(ShortestPathCriteria{From: 0, To: 1, Avoid: ClassToll}); cost != 4 {
+		t.Fatalf("expected the free edge used, got %f", cost)
+	}
+	if cost := g.Dijkstra(ShortestPathCriteria{From: 0, To: 1}); cost != 1 {
+		t.Fatalf("expected the toll edge used, got %f", cost)
+	}
+}
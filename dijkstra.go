@@ -3,6 +3,7 @@ package gograph
 import (
 	"github.com/JesseleDuran/gograph/bitset"
 	"github.com/JesseleDuran/gograph/heap"
+	"github.com/JesseleDuran/gograph/polyline"
 	"github.com/golang/geo/s2"
 	geojson "github.com/paulmach/go.geojson"
 	"math"
@@ -15,6 +16,11 @@ type Previous map[int32]int32
 // Dijkstra is a traditional dijkstra with some conditionals. It can ignore nodes, so it would not pass through those nodes
 // in the search. It also has a max distance to reach, so if it exceeds that value, the search will stop.
 func (g Graph) Dijkstra(s ShortestPathCriteria) float32 {
+	return Dijkstra(&g, s)
+}
+
+// Dijkstra works as Graph.Dijkstra on any network.
+func Dijkstra(n Network, s ShortestPathCriteria) float32 {
 	source, target, pMax := s.From, s.To, s.MaxCost
 	dist := make(Distances, 0)
 	visited := make(map[int32]bool, 0)
@@ -40,11 +46,12 @@ func (g Graph) Dijkstra(s ShortestPathCriteria) float32 {
 			return dist.Cost(target)
 		}
 
-		for _, e := range g.OutgoingEdges[min.Value] {
+		for _, e := range n.Outgoing(min.Value) {
 			// Validate if we can relax the edge related to the possible ignored node ID.
-			if !(g.Nodes[e.ID].Compressed) && !visited[e.ID] {
+			weight, ok := s.weight(e)
+			if ok && !(n.Node(e.ID).Compressed) && !visited[e.ID] {
 				// Relax edge.
-				currentPathValue := dist.Cost(min.Value) + e.Weight
+				currentPathValue := dist.Cost(min.Value) + weight
 				if currentPathValue < dist.Cost(e.ID) {
 					dist[e.ID] = currentPathValue
 					pq.Insert(heap.Node{Value: e.ID, Cost: currentPathValue, Depth: min.Depth + 1})
@@ -68,15 +75,112 @@ func (g Graph) DijkstraPathCoord(source, target Coordinate) (float32, geojson.Fe
 	return d, *fc, data
 }
 
+// Path is the result of a shortest path search.
+// Geometry holds the [lng, lat] coordinates of the path, Edges the ID of each
+// of its edges and Segments their attributes, which is empty when the graph has
+// no attributes.
+// Elevations is the elevation profile of the nodes of the path, with its total
+// Ascent and Descent in meters, only when the graph has elevation. The nodes
+// without elevation are NaN in the profile and the totals go from the last
+// node with one.
+// Polyline is the encoded Geometry, only when the criteria PolylinePrecision
+// is set.
+// Costs is the cost of the search at each node of the path, from the criteria
+// InitialCost at the first node to Cost at the last, with the penalties
+// applied.
+type Path struct {
+	Cost       float32
+	Nodes      []int32
+	Costs      []float32
+	Geometry   [][]float64
+	Polyline   string
+	Data       []uint64
+	Edges      []EdgeID
+	Segments   []EdgeAttributes
+	Elevations []float32
+	Ascent     float32
+	Descent    float32
+}
+
 func (g Graph) DijkstraPath(s ShortestPathCriteria) (float32, [][]float64, []uint64) {
-	source, target, initialCost := s.From, s.To, s.InitialCost
-	dist := make(Distances, 0)
+	p := g.ShortestPath(s)
+	return p.Cost, p.Geometry, p.Data
+}
+
+// ShortestPath works as DijkstraPath but returns the whole Path.
+func (g Graph) ShortestPath(s ShortestPathCriteria) Path {
+	p := ShortestPath(&g, s)
+	if s.From < 0 || s.To < 0 {
+		return p
+	}
+	if g.Attributes != nil {
+		p.Segments = make([]EdgeAttributes, 0, len(p.Edges))
+		for _, id := range p.Edges {
+			attr, _ := g.Attributes.Get(id)
+			p.Segments = append(p.Segments, attr)
+		}
+	}
+	if g.Elevation != nil {
+		p.Elevations = make([]float32, len(p.Nodes))
+		last, known := float32(0), false
+		for i, id := range p.Nodes {
+			e, ok := g.NodeElevation(id)
+			if !ok {
+				p.Elevations[i] = float32(math.NaN())
+				continue
+			}
+			p.Elevations[i] = e
+			if known {
+				if rise := e - last; rise > 0 {
+					p.Ascent += rise
+				} else {
+					p.Descent -= rise
+				}
+			}
+			last, known = e, true
+		}
+	}
+	return p
+}
+
+// ShortestPath works as Graph.ShortestPath on any network, without the
+// segment attributes and elevations.
+func ShortestPath(n Network, s ShortestPathCriteria) Path {
+	source, target := s.From, s.To
 	if source < 0 || target < 0 {
-		return dist.Cost(target), [][]float64{}, []uint64{}
+		return Path{Cost: INFINITE, Nodes: []int32{}, Costs: []float32{}, Geometry: [][]float64{}, Data: []uint64{}}
+	}
+	last, dist, previous, edges, data := dijkstraPath(n, s)
+	p := Path{
+		Cost:     dist.Cost(last),
+		Nodes:    pathNodes(source, last, previous),
+		Geometry: pathPolyline(n, source, last, previous),
+		Data:     data,
+	}
+	p.Edges = make([]EdgeID, 0, len(p.Nodes))
+	for _, id := range p.Nodes[1:] {
+		p.Edges = append(p.Edges, edges[id])
+	}
+	p.Costs = make([]float32, len(p.Nodes))
+	for i, id := range p.Nodes {
+		p.Costs[i] = dist.Cost(id)
 	}
+	if s.PolylinePrecision > 0 {
+		p.Polyline = polyline.Encode(p.Geometry, s.PolylinePrecision)
+	}
+	return p
+}
+
+// dijkstraPath runs the search and returns the last settled node, which is the
+// target if it was reached, with the costs of the nodes and the tree to
+// rebuild the path, with the edge used to reach each node.
+func dijkstraPath(n Network, s ShortestPathCriteria) (int32, Distances, Previous, map[int32]EdgeID, []uint64) {
+	source, target, initialCost := s.From, s.To, s.InitialCost
+	dist := make(Distances, 0)
 	visited := bitset.NewBigInt()
 	dataResult := make([]uint64, 0)
 	previous := make(Previous, 0)
+	edges := make(map[int32]EdgeID)
 
 	// Source node distance to itself is 0.
 	dist[source] = initialCost
@@ -92,47 +196,62 @@ func (g Graph) DijkstraPath(s ShortestPathCriteria) (float32, [][]float64, []uin
 		last = min.Value
 		if !visited.Exists(min.Value) {
 			visited.Set(min.Value, true)
-			dataResult = append(dataResult, g.Nodes[min.Value].Data...)
+			dataResult = append(dataResult, n.Node(min.Value).Data...)
 		}
 		pq.DeleteMin()
 
 		if min.Value == target {
-			return dist.Cost(target), g.pathPolyline(source, target, previous), dataResult
+			return target, dist, previous, edges, dataResult
 		}
 
-		for _, e := range g.OutgoingEdges[min.Value] {
+		for _, e := range n.Outgoing(min.Value) {
 			// Validate if we can relax the edge related to the possible ignored node ID.
-			if !(g.Nodes[e.ID].Compressed) && !visited.Exists(e.ID) {
+			weight, ok := s.weight(e)
+			if ok && !(n.Node(e.ID).Compressed) && !visited.Exists(e.ID) {
 				// Relax edge.
-				currentPathValue := dist.Cost(min.Value) + e.Weight
+				currentPathValue := dist.Cost(min.Value) + weight
 				if currentPathValue < dist.Cost(e.ID) {
 					dist[e.ID] = currentPathValue
 					previous[e.ID] = min.Value
+					edges[e.ID] = e.EdgeID
 					pq.Insert(heap.Node{Value: e.ID, Cost: currentPathValue, Depth: min.Depth + 1})
 				}
 			}
 		}
 	}
-	return dist.Cost(last), g.pathPolyline(source, last, previous), dataResult
+	return last, dist, previous, edges, dataResult
+}
+
+// pathNodes returns the node IDs of the path from start to end.
+func pathNodes(start, end int32, previous Previous) []int32 {
+	result := make([]int32, 0)
+	for pathval := end; pathval != start; pathval = previous[pathval] {
+		result = append(result, pathval)
+	}
+	result = append(result, start)
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
 }
 
-func (g Graph) pathPolyline(start, end int32, previous Previous) [][]float64 {
+func pathPolyline(n Network, start, end int32, previous Previous) [][]float64 {
 	result := make([][]float64, 0)
 	pathval := end
 	result = append(result, []float64{
-		s2.CellID(g.Nodes[end].Location).LatLng().Lng.Degrees(),
-		s2.CellID(g.Nodes[end].Location).LatLng().Lat.Degrees(),
+		s2.CellID(n.Node(end).Location).LatLng().Lng.Degrees(),
+		s2.CellID(n.Node(end).Location).LatLng().Lat.Degrees(),
 	})
 	for pathval != start {
 		result = append(result, []float64{
-			s2.CellID(g.Nodes[pathval].Location).LatLng().Lng.Degrees(),
-			s2.CellID(g.Nodes[pathval].Location).LatLng().Lat.Degrees(),
+			s2.CellID(n.Node(pathval).Location).LatLng().Lng.Degrees(),
+			s2.CellID(n.Node(pathval).Location).LatLng().Lat.Degrees(),
 		})
 		pathval = previous[pathval]
 	}
 	result = append(result, []float64{
-		s2.CellID(g.Nodes[pathval].Location).LatLng().Lng.Degrees(),
-		s2.CellID(g.Nodes[pathval].Location).LatLng().Lat.Degrees(),
+		s2.CellID(n.Node(pathval).Location).LatLng().Lng.Degrees(),
+		s2.CellID(n.Node(pathval).Location).LatLng().Lat.Degrees(),
 	})
 	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
 		result[i], result[j] = result[j], result[i]
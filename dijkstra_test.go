@@ -0,0 +1,96 @@
+package gograph
+
+import (
+	"github.com/JesseleDuran/gograph/polyline"
+	"github.com/golang/geo/s2"
+	"math"
+	"testing"
+)
+
+// testGraph builds a small graph along a street:
+// 0 <-> 1 <-> 2 -> 3, with a shortcut 0 -> 3 heavier than the street.
+func testGraph() Graph {
+	g := Graph{}
+	for i := 0; i < 4; i++ {
+		g.AddNode(Node{Location: uint64(s2.CellIDFromLatLng(s2.LatLngFromDegrees(4.6, -74.08+float64(i)*0.001)))})
+	}
+	g.RelateNodes(g.Nodes[0], g.Nodes[1], 1, Bidirectional)
+	g.RelateNodes(g.Nodes[1], g.Nodes[2], 1, Bidirectional)
+	g.RelateNodes(g.Nodes[2], g.Nodes[3], 1, LeftToRight)
+	g.RelateNodes(g.Nodes[0], g.Nodes[3], 5, LeftToRight)
+	g.EdgeIndex = g.BuildEdgeIndex()
+	return g
+}
+
+func TestGraph_ShortestPath(t *testing.T) {
+	g := testGraph()
+	g.Attributes = NewAttributes()
+	street := EdgeAttributes{Name: "Calle 26", Highway: "primary", Flags: Bridge}
+	for _, id := range []EdgeID{0, 2, 4} {
+		g.Attributes.Set(id, street)
+	}
+	p := g.ShortestPath(ShortestPathCriteria{From: 0, To: 3})
+	if p.Cost != 3 {
+		t.Fatalf("expected cost 3, got %f", p.Cost)
+	}
+	if len(p.Nodes) != 4 || p.Nodes[0] != 0 || p.Nodes[3] != 3 {
+		t.Fatalf("unexpected nodes %v", p.Nodes)
+	}
+	for i, cost := range p.Costs {
+		if len(p.Costs) != 4 || cost != float32(i) {
+			t.Fatalf("unexpected costs %v", p.Costs)
+		}
+	}
+	if len(p.Segments) != 3 || p.Segments[1] != street {
+		t.Fatalf("unexpected segments %v", p.Segments)
+	}
+	if len(g.Attributes.Values) != 1 {
+		t.Fatalf("expected deduplicated attributes, got %d values", len(g.Attributes.Values))
+	}
+	if cost := g.Dijkstra(ShortestPathCriteria{From: 3, To: 0}); cost != INFINITE {
+		t.Fatalf("expected no path from 3 to 0, got %f", cost)
+	}
+}
+
+func TestGraph_ShortestPath_Polyline(t *testing.T) {
+	g := testGraph()
+	if p := g.ShortestPath(ShortestPathCriteria{From: 0, To: 3}); p.Polyline != "" {
+		t.Fatalf("expected no polyline by default, got %s", p.Polyline)
+	}
+	for _, precision := range []int{polyline.Precision5, polyline.Precision6} {
+		p := g.ShortestPath(ShortestPathCriteria{From: 0, To: 3, PolylinePrecision: precision})
+		decoded, err := polyline.Decode(p.Polyline, precision)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(decoded) != len(p.Geometry) {
+			t.Fatalf("expected %d coordinates, got %d", len(p.Geometry), len(decoded))
+		}
+		tolerance := math.Pow(10, -float64(precision)) / 2
+		for i, c := range p.Geometry {
+			if math.Abs(decoded[i][0]-c[0]) > tolerance || math.Abs(decoded[i][1]-c[1]) > tolerance {
+				t.Fatalf("precision %d: expected %v, got %v", precision, c, decoded[i])
+			}
+		}
+	}
+}
+
+func TestGraph_Dijkstra_Avoid(t *testing.T) {
+	g := Graph{}
+	for i := 0; i < 3; i++ {
+		g.AddNode(Node{})
+	}
+	g.RelateNodesClass(g.Nodes[0], g.Nodes[2], 1, LeftToRight, ClassToll|ClassMotorway)
+	g.RelateNodes(g.Nodes[0], g.Nodes[1], 2, LeftToRight)
+	g.RelateNodes(g.Nodes[1], g.Nodes[2], 2, LeftToRight)
+	if cost := g.Dijkstra(ShortestPathCriteria{From: 0, To: 2}); cost != 1 {
+		t.Fatalf("expected the toll road, got %f", cost)
+	}
+	if cost := g.Dijkstra(ShortestPathCriteria{From: 0, To: 2, Avoid: ClassToll}); cost != 4 {
+		t.Fatalf("expected the toll road to be avoided, got %f", cost)
+	}
+	penalties := map[EdgeClass]float32{ClassMotorway: 3}
+	if cost := g.Dijkstra(ShortestPathCriteria{From: 0, To: 2, Penalties: penalties}); cost != 3 {
+		t.Fatalf("expected the penalized motorway, got %f", cost)
+	}
+}
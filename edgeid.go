@@ -0,0 +1,110 @@
+package gograph
+
+// EdgeID identifies an edge in the whole graph, so data like traffic speeds
+// or closures can be attached to it. The IDs are given in creation order and
+// kept when the graph is serialized or its nodes renumbered. Removing an edge
+// leaves its ID unused, IDs are never reused.
+type EdgeID uint32
+
+// removedEdge are the ends of the IDs of the removed edges.
+var removedEdge = EdgeKey{From: -1, To: -1}
+
+// newEdgeID gives an ID to the edge from -> to.
+func (g *Graph) newEdgeID(from, to int32) EdgeID {
+	g.EdgeEnds = append(g.EdgeEnds, EdgeKey{From: from, To: to})
+	return EdgeID(len(g.EdgeEnds) - 1)
+}
+
+// removeEdgeID marks the ID as removed and drops the OSM way reference and
+// attributes of the edge.
+func (g *Graph) removeEdgeID(id EdgeID) {
+	if int(id) < len(g.EdgeEnds) {
+		g.EdgeEnds[id] = removedEdge
+	}
+	g.SetEdgeWay(id, WayRef{})
+	g.Attributes.unset(id)
+}
+
+// EdgeByID returns the nodes related by an edge, false if it does not exist.
+func (g Graph) EdgeByID(id EdgeID) (EdgeKey, bool) {
+	if int(id) >= len(g.EdgeEnds) || g.EdgeEnds[id] == removedEdge {
+		return EdgeKey{}, false
+	}
+	return g.EdgeEnds[id], true
+}
+
+// EdgeOf returns an edge by its ID, in its outgoing form, with the node it
+// leaves. It returns false if the edge does not exist.
+func (g Graph) EdgeOf(id EdgeID) (int32, Edge, bool) {
+	ends, ok := g.EdgeByID(id)
+	if !ok {
+		return 0, Edge{}, false
+	}
+	for _, e := range g.Outgoing(ends.From) {
+		if e.EdgeID == id {
+			return ends.From, e, true
+		}
+	}
+	return 0, Edge{}, false
+}
+
+// RemoveEdgeByID removes an edge, with its OSM way reference and attributes.
+// It returns false if the edge does not exist.
+func (g *Graph) RemoveEdgeByID(id EdgeID) bool {
+	ends, ok := g.EdgeByID(id)
+	if !ok {
+		return false
+	}
+	g.Thaw()
+	removed := false
+	for i, e := range g.OutgoingEdges[ends.From] {
+		if e.EdgeID == id {
+			g.OutgoingEdges[ends.From] = append(g.OutgoingEdges[ends.From][:i], g.OutgoingEdges[ends.From][i+1:]...)
+			removed = true
+			break
+		}
+	}
+	for i, e := range g.IncomingEdges[ends.To] {
+		if e.EdgeID == id {
+			g.IncomingEdges[ends.To] = append(g.IncomingEdges[ends.To][:i], g.IncomingEdges[ends.To][i+1:]...)
+			break
+		}
+	}
+	g.removeEdgeID(id)
+	return removed
+}
+
+// EdgeBetween returns the edge from -> to, the lightest one if the nodes are
+// related several times.
+func (g Graph) EdgeBetween(from, to int32) (Edge, bool) {
+	result, found := Edge{}, false
+	for _, e := range g.Outgoing(from) {
+		if e.ID == to && (!found || e.Weight < result.Weight) {
+			result, found = e, true
+		}
+	}
+	return result, found
+}
+
+// SetEdgeWeightByID changes the weight of an edge. It returns false if the
+// edge does not exist.
+func (g *Graph) SetEdgeWeightByID(id EdgeID, weight float32) bool {
+	ends, ok := g.EdgeByID(id)
+	if !ok {
+		return false
+	}
+	g.detach()
+	out := g.Outgoing(ends.From)
+	for i, e := range out {
+		if e.EdgeID == id {
+			out[i].Weight = weight
+		}
+	}
+	in := g.Incoming(ends.To)
+	for i, e := range in {
+		if e.EdgeID == id {
+			in[i].Weight = weight
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package gograph
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGraph_EdgeID(t *testing.T) {
+	g := testGraph()
+	e, ok := g.EdgeBetween(2, 3)
+	if !ok {
+		t.Fatal("expected the edge 2 -> 3")
+	}
+	if ends, ok := g.EdgeByID(e.EdgeID); !ok || ends != (EdgeKey{From: 2, To: 3}) {
+		t.Fatalf("unexpected ends %v", ends)
+	}
+	if g.IncomingEdges[3][0].EdgeID != e.EdgeID {
+		t.Fatal("expected the incoming edge to share the ID")
+	}
+	p := g.ShortestPath(ShortestPathCriteria{From: 0, To: 3})
+	if len(p.Edges) != 3 || p.Edges[2] != e.EdgeID {
+		t.Fatalf("unexpected edges %v", p.Edges)
+	}
+
+	g.SetEdgeWeightByID(e.EdgeID, 10)
+	if cost := g.Dijkstra(ShortestPathCriteria{From: 0, To: 3}); cost != 5 {
+		t.Fatalf("expected the shortcut, got %f", cost)
+	}
+	removed, _ := g.EdgeBetween(0, 1)
+	g.RemoveEdge(0, 1)
+	if _, ok := g.EdgeByID(removed.EdgeID); ok {
+		t.Fatal("expected the ID of the removed edge unused")
+	}
+
+	// the IDs survive the serialization and the renumbering.
+	var b bytes.Buffer
+	if _, err := g.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	r := Graph{}
+	if _, err := r.ReadFrom(&b); err != nil || !reflect.DeepEqual(r.EdgeEnds, g.EdgeEnds) {
+		t.Fatalf("unexpected edge ends %v: %v", r.EdgeEnds, err)
+	}
+	path := filepath.Join(t.TempDir(), "graph.bin")
+	if err := g.SerializeAs(path, Binary); err != nil {
+		t.Fatal(err)
+	}
+	m, err := MapFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if found, _ := m.EdgeBetween(2, 3); found.EdgeID != e.EdgeID || !reflect.DeepEqual(m.EdgeEnds, g.EdgeEnds) {
+		t.Fatalf("unexpected mapped edge %v", found)
+	}
+	g.Renumber([]int32{3, 2, 1, 0})
+	if ends, _ := g.EdgeByID(e.EdgeID); ends != (EdgeKey{From: 1, To: 0}) {
+		t.Fatalf("expected the renumbered ends, got %v", ends)
+	}
+}
+
+func TestGraph_RemoveEdgeByID(t *testing.T) {
+	g := testGraph()
+	g.Attributes = NewAttributes()
+	// a parallel edge from another way, with its own reference and attributes.
+	parallel := g.AddEdge(0, 1, 3, ClassToll)
+	g.SetEdgeWay(0, WayRef{ID: 10})
+	g.SetEdgeWay(parallel, WayRef{ID: 20})
+	g.Attributes.Set(0, EdgeAttributes{Name: "Calle 26"})
+	g.Attributes.Set(parallel, EdgeAttributes{Name: "Autopista"})
+
+	if from, e, ok := g.EdgeOf(parallel); !ok || from != 0 || e.ID != 1 || e.Class != ClassToll {
+		t.Fatalf("unexpected edge %d %v", from, e)
+	}
+	if !g.RemoveEdgeByID(parallel) || g.RemoveEdgeByID(parallel) {
+		t.Fatal("expected the edge removed once")
+	}
+	if len(g.Outgoing(0)) != 2 || len(g.Incoming(1)) != 2 {
+		t.Fatalf("expected only the parallel edge removed, got %v", g.Outgoing(0))
+	}
+	if _, ok := g.EdgeWay(parallel); ok {
+		t.Fatal("expected the way of the removed edge dropped")
+	}
+	if ref, _ := g.EdgeWay(0); ref.ID != 10 {
+		t.Fatalf("expected the way of the other edge kept, got %v", ref)
+	}
+	if attr, _ := g.EdgeAttributes(0); attr.Name != "Calle 26" || g.Attributes.Len() != 1 {
+		t.Fatalf("unexpected attributes %v", g.Attributes.Edges)
+	}
+}
@@ -0,0 +1,72 @@
+// Package elevation samples the elevation of the graph nodes from local
+// raster files, SRTM .hgt tiles or GeoTIFF images, and offers weights that
+// depend on the grade of the edges.
+package elevation
+
+import (
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/golang/geo/s2"
+	"math"
+)
+
+// DefaultClimbFactor makes a 1% uphill grade cost 10% more than flat ground.
+const DefaultClimbFactor = 10.0
+
+// Source gives the elevation in meters of a coordinate.
+type Source interface {
+	Elevation(lat, lng float64) (float64, bool)
+}
+
+// Sources tries each source in order until one knows the elevation.
+type Sources []Source
+
+func (s Sources) Elevation(lat, lng float64) (float64, bool) {
+	for _, src := range s {
+		if e, ok := src.Elevation(lat, lng); ok {
+			return e, true
+		}
+	}
+	return 0, false
+}
+
+// Sample stores in the graph the elevation of each node and returns the
+// number of nodes the source has no elevation for, which are left NaN as
+// Graph.Elevation documents.
+func Sample(g *graph.Graph, src Source) int {
+	missing := 0
+	g.Elevation = make([]float32, len(g.Nodes))
+	for i, n := range g.Nodes {
+		ll := s2.CellID(n.Location).LatLng()
+		e, ok := src.Elevation(ll.Lat.Degrees(), ll.Lng.Degrees())
+		if !ok {
+			g.Elevation[i] = float32(math.NaN())
+			missing++
+			continue
+		}
+		g.Elevation[i] = float32(e)
+	}
+	return missing
+}
+
+// Cycling returns a weight that penalizes the ascent of the edges, to use with
+// Graph.Reweight once the elevation is sampled. An edge with an uphill grade
+// costs 1 + climbFactor*grade times its weight, descents are not rewarded. The
+// edges from or to a node without elevation are taken as flat.
+func Cycling(g graph.Graph, climbFactor float64) graph.WeightFunc {
+	return func(from, to int32, weight float32) float32 {
+		a, okA := g.NodeElevation(from)
+		b, okB := g.NodeElevation(to)
+		if !okA || !okB {
+			return weight
+		}
+		rise := float64(b - a)
+		if rise <= 0 {
+			return weight
+		}
+		meters := float64(graph.Distance(s2.CellID(g.Nodes[from].Location), s2.CellID(g.Nodes[to].Location)))
+		if meters == 0 {
+			return weight
+		}
+		return weight * float32(1+climbFactor*rise/meters)
+	}
+}
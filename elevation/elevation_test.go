@@ -0,0 +1,157 @@
+package elevation
+
+import (
+	"bytes"
+	"encoding/binary"
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/golang/geo/s2"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeHGT writes a SRTM3 tile where the elevation grows one meter per
+// column from west to east.
+func writeHGT(t *testing.T, dir string, south, west int) {
+	const size = 1201
+	data := make([]byte, size*size*2)
+	for r := 0; r < size; r++ {
+		for c := 0; c < size; c++ {
+			binary.BigEndian.PutUint16(data[(r*size+c)*2:], uint16(c))
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, HGTName(south, west)), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHGT_Elevation(t *testing.T) {
+	dir := t.TempDir()
+	writeHGT(t, dir, 4, -75)
+	src := NewHGT(dir)
+	e, ok := src.Elevation(4.5, -74.5)
+	if !ok || math.Abs(e-600) > 1e-6 {
+		t.Fatalf("expected 600, got %f %v", e, ok)
+	}
+	e, ok = src.Elevation(4.2, -74.99958333)
+	if !ok || math.Abs(e-0.5) > 0.01 {
+		t.Fatalf("expected 0.5, got %f %v", e, ok)
+	}
+	if _, ok := src.Elevation(5.5, -74.5); ok {
+		t.Fatal("expected no elevation out of the tiles")
+	}
+}
+
+func TestReadGeoTIFF(t *testing.T) {
+	// a 2x2 float32 image of 1 degree pixels with its upper left corner at 5N 75W.
+	var b bytes.Buffer
+	le := binary.LittleEndian
+	b.WriteString("II")
+	_ = binary.Write(&b, le, uint16(42))
+	_ = binary.Write(&b, le, uint32(8))
+	type entry struct {
+		tag, kind uint16
+		count     uint32
+		value     uint32
+	}
+	entries := []entry{
+		{tagImageWidth, 3, 1, 2},
+		{tagImageLength, 3, 1, 2},
+		{tagBitsPerSample, 3, 1, 32},
+		{tagCompression, 3, 1, 1},
+		{tagStripOffsets, 4, 1, 0},
+		{tagSamplesPerPixel, 3, 1, 1},
+		{tagStripByteCounts, 4, 1, 16},
+		{tagSampleFormat, 3, 1, 3},
+		{tagModelPixelScale, 12, 3, 0},
+		{tagModelTiepoint, 12, 6, 0},
+	}
+	dataAt := uint32(8 + 2 + len(entries)*12 + 4)
+	scaleAt, tieAt, pixelsAt := dataAt, dataAt+24, dataAt+72
+	entries[4].value, entries[8].value, entries[9].value = pixelsAt, scaleAt, tieAt
+	_ = binary.Write(&b, le, uint16(len(entries)))
+	for _, e := range entries {
+		_ = binary.Write(&b, le, e)
+	}
+	_ = binary.Write(&b, le, uint32(0))
+	_ = binary.Write(&b, le, []float64{1, 1, 0})
+	_ = binary.Write(&b, le, []float64{0, 0, 0, -75, 5, 0})
+	_ = binary.Write(&b, le, []float32{100, 200, 300, 400})
+	path := filepath.Join(t.TempDir(), "dem.tif")
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dem, err := ReadGeoTIFF(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := dem.Elevation(4, -74)
+	if !ok || math.Abs(e-250) > 1e-6 {
+		t.Fatalf("expected 250 at the center, got %f %v", e, ok)
+	}
+	e, ok = dem.Elevation(4.5, -74.5)
+	if !ok || math.Abs(e-100) > 1e-6 {
+		t.Fatalf("expected 100 at the center of the first pixel, got %f %v", e, ok)
+	}
+}
+
+func TestCycling(t *testing.T) {
+	dir := t.TempDir()
+	writeHGT(t, dir, 4, -75)
+	g := graph.Graph{}
+	for _, lng := range []float64{-74.5, -74.49} {
+		g.AddNode(graph.Node{Location: uint64(s2.CellIDFromLatLng(s2.LatLngFromDegrees(4.5, lng)))})
+	}
+	g.RelateNodes(g.Nodes[0], g.Nodes[1], 100, graph.Bidirectional)
+	if missing := Sample(&g, NewHGT(dir)); missing != 0 {
+		t.Fatalf("expected all nodes sampled, %d missing", missing)
+	}
+	g.Reweight(Cycling(g, DefaultClimbFactor))
+	up, down := g.OutgoingEdges[0][0].Weight, g.OutgoingEdges[1][0].Weight
+	if down != 100 || up <= 100 {
+		t.Fatalf("expected the ascent to be penalized, got up %f down %f", up, down)
+	}
+	if g.IncomingEdges[1][0].Weight != up {
+		t.Fatalf("expected incoming edges reweighted as the outgoing ones")
+	}
+	p := g.ShortestPath(graph.ShortestPathCriteria{From: 0, To: 1})
+	if len(p.Elevations) != 2 || p.Ascent < 11 || p.Descent != 0 {
+		t.Fatalf("unexpected profile %v ascent %f descent %f", p.Elevations, p.Ascent, p.Descent)
+	}
+}
+
+func TestCycling_MissingElevation(t *testing.T) {
+	dir := t.TempDir()
+	writeHGT(t, dir, 4, -75)
+	location := func(lng float64) uint64 {
+		return uint64(s2.CellIDFromLatLng(s2.LatLngFromDegrees(4.5, lng)))
+	}
+	a := graph.Graph{}
+	for _, lng := range []float64{-74.5, -74.49} {
+		a.AddNode(graph.Node{Location: location(lng)})
+	}
+	a.RelateNodes(a.Nodes[0], a.Nodes[1], 100, graph.Bidirectional)
+	Sample(&a, NewHGT(dir))
+	// b continues from the node 1 of a and has no elevation.
+	b := graph.Graph{}
+	for _, lng := range []float64{-74.49, -74.48} {
+		b.AddNode(graph.Node{Location: location(lng)})
+	}
+	b.RelateNodes(b.Nodes[0], b.Nodes[1], 100, graph.Bidirectional)
+
+	g := graph.Merge(a, b)
+	if _, ok := g.NodeElevation(2); ok || len(g.Elevation) != 3 {
+		t.Fatalf("expected no elevation for the node of b, got %v", g.Elevation)
+	}
+	g.Reweight(Cycling(g, DefaultClimbFactor))
+	p := g.ShortestPath(graph.ShortestPathCriteria{From: 0, To: 2})
+	if len(p.Nodes) != 3 || p.Cost <= 200 || p.Cost > 300 {
+		t.Fatalf("expected a route across the border, got %v cost %f", p.Nodes, p.Cost)
+	}
+	p = g.ShortestPath(graph.ShortestPathCriteria{From: 2, To: 0})
+	if len(p.Nodes) != 3 || p.Cost != 200 || p.Descent < 11 || p.Ascent != 0 {
+		t.Fatalf("unexpected route %v cost %f ascent %f descent %f", p.Nodes, p.Cost, p.Ascent, p.Descent)
+	}
+}
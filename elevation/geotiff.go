@@ -0,0 +1,227 @@
+package elevation
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TIFF tags used to read a GeoTIFF elevation model.
+const (
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagStripByteCounts = 279
+	tagSampleFormat    = 339
+	tagModelPixelScale = 33550
+	tagModelTiepoint   = 33922
+	tagGDALNoData      = 42113
+)
+
+// ErrUnsupportedTIFF is returned for the files ReadGeoTIFF can not read.
+var ErrUnsupportedTIFF = errors.New("elevation: unsupported GeoTIFF, only uncompressed single band strips are read")
+
+// GeoTIFF is an elevation model read from a GeoTIFF file in geographic
+// coordinates (EPSG:4326). Only uncompressed, single band, strip organized
+// files are supported, which is what gdal_translate writes by default.
+type GeoTIFF struct {
+	width, height int
+	// west and north are the coordinates of the upper left corner of the
+	// image, dx and dy the size of a pixel in degrees.
+	west, north, dx, dy float64
+	noData              float64
+	hasNoData           bool
+	samples             []float32
+}
+
+// ReadGeoTIFF loads a GeoTIFF file into memory.
+func ReadGeoTIFF(path string) (*GeoTIFF, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8 {
+		return nil, fmt.Errorf("elevation: %s is not a TIFF file", path)
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("elevation: %s is not a TIFF file", path)
+	}
+	if order.Uint16(data[2:]) != 42 {
+		return nil, fmt.Errorf("elevation: %s is not a classic TIFF file", path)
+	}
+	tags, err := readIFD(data, order, int(order.Uint32(data[4:])))
+	if err != nil {
+		return nil, fmt.Errorf("elevation: %s: %w", path, err)
+	}
+
+	t := &GeoTIFF{}
+	t.width, t.height = int(tags.uint(tagImageWidth, 0)), int(tags.uint(tagImageLength, 0))
+	bits := int(tags.uint(tagBitsPerSample, 1))
+	format := tags.uint(tagSampleFormat, 1)
+	if tags.uint(tagCompression, 1) != 1 || tags.uint(tagSamplesPerPixel, 1) != 1 || len(tags[tagStripOffsets].values) == 0 {
+		return nil, ErrUnsupportedTIFF
+	}
+	scale, tie := tags.float64s(tagModelPixelScale), tags.float64s(tagModelTiepoint)
+	if len(scale) < 2 || len(tie) < 6 {
+		return nil, fmt.Errorf("elevation: %s has no georeference", path)
+	}
+	t.dx, t.dy = scale[0], scale[1]
+	t.west, t.north = tie[3]-tie[0]*t.dx, tie[4]+tie[1]*t.dy
+	if v, ok := tags[tagGDALNoData]; ok {
+		noData, err := strconv.ParseFloat(strings.Trim(string(v.bytes), "\x00 "), 64)
+		t.noData, t.hasNoData = noData, err == nil
+	}
+
+	sample, err := sampleReader(bits, format, order)
+	if err != nil {
+		return nil, err
+	}
+	size := bits / 8
+	t.samples = make([]float32, 0, t.width*t.height)
+	offsets, counts := tags[tagStripOffsets].values, tags[tagStripByteCounts].values
+	for i, offset := range offsets {
+		if i >= len(counts) || int(offset)+int(counts[i]) > len(data) {
+			return nil, fmt.Errorf("elevation: %s has a truncated strip", path)
+		}
+		strip := data[int(offset) : int(offset)+int(counts[i])]
+		for j := 0; j+size <= len(strip) && len(t.samples) < cap(t.samples); j += size {
+			t.samples = append(t.samples, sample(strip[j:]))
+		}
+	}
+	if len(t.samples) != t.width*t.height {
+		return nil, fmt.Errorf("elevation: %s has %d samples, expected %d", path, len(t.samples), t.width*t.height)
+	}
+	return t, nil
+}
+
+// Elevation interpolates the elevation of the coordinate from the four
+// pixels around it, taking the value of a pixel at its center.
+func (t *GeoTIFF) Elevation(lat, lng float64) (float64, bool) {
+	row := (t.north-lat)/t.dy - 0.5
+	col := (lng-t.west)/t.dx - 0.5
+	if row < -0.5 || col < -0.5 || row > float64(t.height)-0.5 || col > float64(t.width)-0.5 {
+		return 0, false
+	}
+	row, col = math.Max(row, 0), math.Max(col, 0)
+	size := t.width
+	if t.height > size {
+		size = t.height
+	}
+	return bilinear(row, col, size, func(r, c int) (float64, bool) {
+		if r >= t.height || c >= t.width {
+			return 0, false
+		}
+		v := float64(t.samples[r*t.width+c])
+		if math.IsNaN(v) || (t.hasNoData && v == t.noData) {
+			return 0, false
+		}
+		return v, true
+	})
+}
+
+// sampleReader decodes a sample of the given bits and TIFF sample format:
+// 1 unsigned integer, 2 signed integer and 3 floating point.
+func sampleReader(bits int, format uint32, order binary.ByteOrder) (func([]byte) float32, error) {
+	switch {
+	case format == 3 && bits == 32:
+		return func(b []byte) float32 { return math.Float32frombits(order.Uint32(b)) }, nil
+	case format == 3 && bits == 64:
+		return func(b []byte) float32 { return float32(math.Float64frombits(order.Uint64(b))) }, nil
+	case format == 2 && bits == 16:
+		return func(b []byte) float32 { return float32(int16(order.Uint16(b))) }, nil
+	case format == 2 && bits == 32:
+		return func(b []byte) float32 { return float32(int32(order.Uint32(b))) }, nil
+	case format == 1 && bits == 16:
+		return func(b []byte) float32 { return float32(order.Uint16(b)) }, nil
+	case format == 1 && bits == 8:
+		return func(b []byte) float32 { return float32(b[0]) }, nil
+	}
+	return nil, ErrUnsupportedTIFF
+}
+
+// tiffTag is the value of a TIFF tag, kept both as integers and raw bytes.
+type tiffTag struct {
+	values []uint32
+	bytes  []byte
+	order  binary.ByteOrder
+	kind   uint16
+}
+
+type tiffTags map[uint16]tiffTag
+
+// uint returns the first value of a tag, or the default if missing.
+func (t tiffTags) uint(tag uint16, def uint32) uint32 {
+	if v, ok := t[tag]; ok && len(v.values) > 0 {
+		return v.values[0]
+	}
+	return def
+}
+
+// float64s returns the values of a DOUBLE tag.
+func (t tiffTags) float64s(tag uint16) []float64 {
+	v, ok := t[tag]
+	if !ok || v.kind != 12 {
+		return nil
+	}
+	result := make([]float64, len(v.bytes)/8)
+	for i := range result {
+		result[i] = math.Float64frombits(v.order.Uint64(v.bytes[i*8:]))
+	}
+	return result
+}
+
+// typeSizes are the byte sizes of the TIFF field types.
+var typeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+// readIFD reads the first image file directory.
+func readIFD(data []byte, order binary.ByteOrder, offset int) (tiffTags, error) {
+	if offset+2 > len(data) {
+		return nil, errors.New("truncated directory")
+	}
+	n := int(order.Uint16(data[offset:]))
+	tags := make(tiffTags, n)
+	for i := 0; i < n; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(data) {
+			return nil, errors.New("truncated directory")
+		}
+		tag, kind := order.Uint16(data[entry:]), order.Uint16(data[entry+2:])
+		count := int(order.Uint32(data[entry+4:]))
+		size, ok := typeSizes[kind]
+		if !ok {
+			continue
+		}
+		raw := data[entry+8 : entry+12]
+		if size*count > 4 {
+			at := int(order.Uint32(raw))
+			if at+size*count > len(data) {
+				return nil, errors.New("truncated tag value")
+			}
+			raw = data[at : at+size*count]
+		}
+		value := tiffTag{bytes: raw[:size*count], order: order, kind: kind}
+		for j := 0; j < count; j++ {
+			switch kind {
+			case 3:
+				value.values = append(value.values, uint32(order.Uint16(raw[j*2:])))
+			case 4:
+				value.values = append(value.values, order.Uint32(raw[j*4:]))
+			}
+		}
+		tags[tag] = value
+	}
+	return tags, nil
+}
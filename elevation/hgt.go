@@ -0,0 +1,132 @@
+package elevation
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// void is the value of the SRTM samples without data.
+const void = -32768
+
+// HGT reads the SRTM .hgt tiles of a directory. Each tile covers one degree
+// and is named after its south west corner, like N04W075.hgt. Tiles are
+// loaded the first time they are needed and kept in memory.
+type HGT struct {
+	dir   string
+	mu    sync.Mutex
+	tiles map[[2]int]*hgtTile
+}
+
+type hgtTile struct {
+	size    int
+	samples []int16
+}
+
+// NewHGT creates a source of the .hgt tiles in the given directory.
+func NewHGT(dir string) *HGT {
+	return &HGT{dir: dir, tiles: make(map[[2]int]*hgtTile)}
+}
+
+// Elevation interpolates the elevation of the coordinate from the four
+// samples around it. It returns false if the tile is missing or the samples
+// are void.
+func (h *HGT) Elevation(lat, lng float64) (float64, bool) {
+	south, west := int(math.Floor(lat)), int(math.Floor(lng))
+	t := h.tile(south, west)
+	if t == nil {
+		return 0, false
+	}
+	// rows go from north to south and columns from west to east.
+	last := float64(t.size - 1)
+	row := (float64(south+1) - lat) * last
+	col := (lng - float64(west)) * last
+	return bilinear(row, col, t.size, func(r, c int) (float64, bool) {
+		v := t.samples[r*t.size+c]
+		return float64(v), v != void
+	})
+}
+
+func (h *HGT) tile(south, west int) *hgtTile {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := [2]int{south, west}
+	if t, ok := h.tiles[key]; ok {
+		return t
+	}
+	t, err := readHGT(filepath.Join(h.dir, HGTName(south, west)))
+	if err != nil {
+		// missing tiles are remembered so they are not looked up again.
+		t = nil
+	}
+	h.tiles[key] = t
+	return t
+}
+
+// HGTName returns the name of the tile with the given south west corner.
+func HGTName(south, west int) string {
+	ns, ew := 'N', 'E'
+	if south < 0 {
+		ns, south = 'S', -south
+	}
+	if west < 0 {
+		ew, west = 'W', -west
+	}
+	return fmt.Sprintf("%c%02d%c%03d.hgt", ns, south, ew, west)
+}
+
+// readHGT reads a tile of 1201x1201 (SRTM3) or 3601x3601 (SRTM1) big endian samples.
+func readHGT(path string) (*hgtTile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	size := int(math.Sqrt(float64(len(data) / 2)))
+	if size*size*2 != len(data) {
+		return nil, fmt.Errorf("elevation: %s is not a square .hgt tile", path)
+	}
+	samples := make([]int16, size*size)
+	for i := range samples {
+		samples[i] = int16(binary.BigEndian.Uint16(data[i*2:]))
+	}
+	return &hgtTile{size: size, samples: samples}, nil
+}
+
+// bilinear interpolates the value at a fractional row and column of a square
+// grid, skipping the samples without data.
+func bilinear(row, col float64, size int, sample func(r, c int) (float64, bool)) (float64, bool) {
+	r0, c0 := int(math.Floor(row)), int(math.Floor(col))
+	if r0 < 0 || c0 < 0 || r0 >= size || c0 >= size {
+		return 0, false
+	}
+	r1, c1 := r0+1, c0+1
+	if r1 >= size {
+		r1 = r0
+	}
+	if c1 >= size {
+		c1 = c0
+	}
+	dr, dc := row-float64(r0), col-float64(c0)
+	total, weights := 0.0, 0.0
+	for _, s := range [4]struct {
+		r, c int
+		w    float64
+	}{
+		{r0, c0, (1 - dr) * (1 - dc)},
+		{r0, c1, (1 - dr) * dc},
+		{r1, c0, dr * (1 - dc)},
+		{r1, c1, dr * dc},
+	} {
+		if v, ok := sample(s.r, s.c); ok {
+			total += v * s.w
+			weights += s.w
+		}
+	}
+	if weights == 0 {
+		return 0, false
+	}
+	return total / weights, true
+}
@@ -0,0 +1,138 @@
+package gograph
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"io"
+	"strconv"
+)
+
+// ExportOptions restrict what WriteDOT and WriteGraphML write, so the output
+// of a large graph stays manageable. The nodes keep their IDs.
+type ExportOptions struct {
+	// Nodes restricts the output to the given nodes and the edges between
+	// them. All the nodes are written when it is nil. An ID that is not a
+	// node of the graph is an error.
+	Nodes []int32
+	// Bounds restricts the output to the nodes inside and the edges between
+	// them. The nodes are not restricted when it is nil.
+	Bounds *s2.Rect
+}
+
+// included returns which nodes are written.
+func (g Graph) included(opts ExportOptions) ([]bool, error) {
+	keep := make([]bool, len(g.Nodes))
+	if opts.Nodes == nil {
+		for i := range keep {
+			keep[i] = true
+		}
+	}
+	for _, id := range opts.Nodes {
+		if id < 0 || int(id) >= len(g.Nodes) {
+			return nil, fmt.Errorf("gograph: node %d out of range", id)
+		}
+		keep[id] = true
+	}
+	if opts.Bounds != nil {
+		for i, n := range g.Nodes {
+			keep[i] = keep[i] && opts.Bounds.ContainsLatLng(s2.CellID(n.Location).LatLng())
+		}
+	}
+	return keep, nil
+}
+
+// exportDirection returns whether the edge from a to b has a reverse edge,
+// as bidirectional, or goes only from a to b, as left_to_right.
+func (g *Graph) exportDirection(a, b int32) string {
+	dir, _ := EdgeDirectionByNodes(g, a, b)
+	return dir.String()
+}
+
+// WriteDOT writes the graph to w in the Graphviz DOT language, as a digraph
+// with the lat and lng of each node, and the cost, direction and EdgeID of
+// each edge. The weight is written as cost since the weight attribute of
+// Graphviz is an integer that changes the layout. The pos of the nodes is
+// their location, for the neato and fdp layouts.
+func (g Graph) WriteDOT(w io.Writer, opts ExportOptions) error {
+	keep, err := g.included(opts)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph gograph {")
+	for i := range g.Nodes {
+		if !keep[i] {
+			continue
+		}
+		ll := s2.CellID(g.Nodes[i].Location).LatLng()
+		lat, lng := exportDegrees(ll.Lat), exportDegrees(ll.Lng)
+		fmt.Fprintf(bw, "  %d [lat=%s, lng=%s, pos=\"%s,%s!\"];\n", i, lat, lng, lng, lat)
+	}
+	for i := range g.Nodes {
+		if !keep[i] {
+			continue
+		}
+		for _, e := range g.Outgoing(int32(i)) {
+			if keep[e.ID] {
+				fmt.Fprintf(bw, "  %d -> %d [cost=%g, direction=%s, edge_id=%d];\n",
+					i, e.ID, e.Weight, g.exportDirection(int32(i), e.ID), e.EdgeID)
+			}
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// WriteGraphML writes the graph to w in GraphML, as a directed graph with the
+// lat, lng and OSM ID of each node, and the weight, direction and EdgeID of
+// each edge.
+// The nodes are named n followed by their ID.
+func (g Graph) WriteGraphML(w io.Writer, opts ExportOptions) error {
+	keep, err := g.included(opts)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="lat" for="node" attr.name="lat" attr.type="double"/>
+  <key id="lng" for="node" attr.name="lng" attr.type="double"/>
+  <key id="osm_id" for="node" attr.name="osm_id" attr.type="long"/>
+  <key id="weight" for="edge" attr.name="weight" attr.type="double"/>
+  <key id="direction" for="edge" attr.name="direction" attr.type="string"/>
+  <key id="edge_id" for="edge" attr.name="edge_id" attr.type="long"/>
+  <graph id="gograph" edgedefault="directed">
+`)
+	for i := range g.Nodes {
+		if !keep[i] {
+			continue
+		}
+		ll := s2.CellID(g.Nodes[i].Location).LatLng()
+		fmt.Fprintf(bw, "    <node id=\"n%d\"><data key=\"lat\">%s</data><data key=\"lng\">%s</data>", i, exportDegrees(ll.Lat), exportDegrees(ll.Lng))
+		if osmID, ok := g.OSMNodeID(int32(i)); ok {
+			fmt.Fprintf(bw, "<data key=\"osm_id\">%d</data>", osmID)
+		}
+		fmt.Fprintln(bw, "</node>")
+	}
+	for i := range g.Nodes {
+		if !keep[i] {
+			continue
+		}
+		for _, e := range g.Outgoing(int32(i)) {
+			if keep[e.ID] {
+				fmt.Fprintf(bw, "    <edge source=\"n%d\" target=\"n%d\"><data key=\"weight\">%g</data><data key=\"direction\">%s</data><data key=\"edge_id\">%d</data></edge>\n",
+					i, e.ID, e.Weight, g.exportDirection(int32(i), e.ID), e.EdgeID)
+			}
+		}
+	}
+	fmt.Fprint(bw, "  </graph>\n</graphml>\n")
+	return bw.Flush()
+}
+
+// exportDegrees formats an angle in degrees rounded to 7 decimals, about a
+// centimeter, as the leaf cell of a location is not exactly at the point.
+func exportDegrees(a s1.Angle) string {
+	return strconv.FormatFloat(toFixed(a.Degrees(), 7), 'f', -1, 64)
+}
@@ -0,0 +1,73 @@
+package gograph
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestGraph_WriteDOT(t *testing.T) {
+	g := testGraph()
+	buf := &bytes.Buffer{}
+	if err := g.WriteDOT(buf, ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	if !strings.HasPrefix(dot, "digraph gograph {\n") || strings.Count(dot, "->") != 6 {
+		t.Fatalf("unexpected graph\n%s", dot)
+	}
+	if !strings.Contains(dot, "  0 -> 3 [cost=5, direction=left_to_right, edge_id=5];\n") || !strings.Contains(dot, "  1 [lat=4.6, lng=-74.079, ") {
+		t.Fatalf("unexpected graph\n%s", dot)
+	}
+
+	buf.Reset()
+	if err := g.WriteDOT(buf, ExportOptions{Nodes: []int32{2, 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if dot := buf.String(); strings.Count(dot, "->") != 1 || strings.Contains(dot, "  0 [") {
+		t.Fatalf("expected only the edge 2 -> 3\n%s", dot)
+	}
+	if err := g.WriteDOT(buf, ExportOptions{Nodes: []int32{2, 4}}); err == nil {
+		t.Fatal("expected an error for a node out of range")
+	}
+}
+
+func TestGraph_WriteGraphML(t *testing.T) {
+	g := testGraph()
+	g.SetOSMNode(1, 100)
+	buf := &bytes.Buffer{}
+	if err := g.WriteGraphML(buf, ExportOptions{Nodes: []int32{0, 1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	type data struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+	doc := struct {
+		Nodes []struct {
+			ID   string `xml:"id,attr"`
+			Data []data `xml:"data"`
+		} `xml:"graph>node"`
+		Edges []struct {
+			Source string `xml:"source,attr"`
+			Target string `xml:"target,attr"`
+			Data   []data `xml:"data"`
+		} `xml:"graph>edge"`
+	}{}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Nodes) != 3 || len(doc.Edges) != 4 {
+		t.Fatalf("expected 3 nodes and 4 edges, got %d and %d", len(doc.Nodes), len(doc.Edges))
+	}
+	if n := doc.Nodes[1]; n.ID != "n1" || len(n.Data) != 3 || n.Data[2].Value != "100" {
+		t.Fatalf("unexpected node %v", n)
+	}
+	if e := doc.Edges[0]; e.Source != "n0" || e.Target != "n1" || e.Data[0].Value != "1" || e.Data[1].Value != "bidirectional" {
+		t.Fatalf("unexpected edge %v", e)
+	}
+	if err := g.WriteGraphML(buf, ExportOptions{Nodes: []int32{-1}}); err == nil {
+		t.Fatal("expected an error for a node out of range")
+	}
+}
@@ -0,0 +1,119 @@
+package gograph
+
+import (
+	"bufio"
+	"github.com/golang/geo/s2"
+	geojson "github.com/paulmach/go.geojson"
+	"io"
+)
+
+// GeoJSONOptions choose what WriteGeoJSON writes.
+type GeoJSONOptions struct {
+	// Nodes also writes the nodes as Points, with their degree and data.
+	Nodes bool
+	// Bounds restricts the output to the nodes inside and the edges with an
+	// end inside. Everything is written when it is nil.
+	Bounds *s2.Rect
+}
+
+// WriteGeoJSON writes the graph to w as a GeoJSON FeatureCollection, one
+// feature at a time so the whole graph is never held in memory. Each pair of
+// related nodes is written once as a LineString, with the direction of its
+// edges and the weight, EdgeID, attributes and OSM way of the edge from ->
+// to.
+func (g Graph) WriteGeoJSON(w io.Writer, opts GeoJSONOptions) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(`{"type":"FeatureCollection","features":[`)
+	first := true
+	write := func(f *geojson.Feature) error {
+		data, err := f.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if !first {
+			bw.WriteByte(',')
+		}
+		first = false
+		bw.WriteString("\n")
+		_, err = bw.Write(data)
+		return err
+	}
+	inside := func(id int32) bool {
+		return opts.Bounds == nil || opts.Bounds.ContainsLatLng(s2.CellID(g.Nodes[id].Location).LatLng())
+	}
+
+	for i := range g.Nodes {
+		from := int32(i)
+		for _, e := range g.Outgoing(from) {
+			dir, _ := g.EdgeDirectionByNodes(from, e.ID)
+			if dir == Bidirectional && e.ID < from || !inside(from) && !inside(e.ID) {
+				continue
+			}
+			f := geojson.NewLineStringFeature([][]float64{g.lngLat(from), g.lngLat(e.ID)})
+			f.Properties = g.edgeProperties(from, e, dir)
+			if err := write(f); err != nil {
+				return err
+			}
+		}
+	}
+	if opts.Nodes {
+		for i, n := range g.Nodes {
+			id := int32(i)
+			if !inside(id) {
+				continue
+			}
+			f := geojson.NewPointFeature(g.lngLat(id))
+			f.Properties = map[string]interface{}{
+				"id":         id,
+				"degree":     g.DegreeNode(id),
+				"compressed": n.Compressed,
+				"data":       n.Data,
+			}
+			if osmID, ok := g.OSMNodeID(id); ok {
+				f.Properties["osm_id"] = osmID
+			}
+			if e, ok := g.NodeElevation(id); ok {
+				f.Properties["elevation"] = e
+			}
+			if err := write(f); err != nil {
+				return err
+			}
+		}
+	}
+	bw.WriteString("\n]}\n")
+	return bw.Flush()
+}
+
+// lngLat returns the [lng, lat] coordinates of a node.
+func (g Graph) lngLat(id int32) []float64 {
+	ll := s2.CellID(g.Nodes[id].Location).LatLng()
+	return []float64{ll.Lng.Degrees(), ll.Lat.Degrees()}
+}
+
+// edgeProperties returns the properties written for the edge from -> e.ID.
+func (g Graph) edgeProperties(from int32, e Edge, dir EdgeDirection) map[string]interface{} {
+	p := map[string]interface{}{
+		"from":       from,
+		"to":         e.ID,
+		"edge_id":    e.EdgeID,
+		"weight":     e.Weight,
+		"direction":  dir.String(),
+		"class":      e.Class,
+		"compressed": g.Nodes[from].Compressed || g.Nodes[e.ID].Compressed,
+	}
+	if attr, ok := g.EdgeAttributes(e.EdgeID); ok {
+		p["name"] = attr.Name
+		p["ref"] = attr.Ref
+		p["highway"] = attr.Highway
+		p["surface"] = attr.Surface
+		p["lanes"] = attr.Lanes
+		p["maxspeed"] = attr.MaxSpeed
+		p["toll"] = attr.Flags.Has(Toll)
+		p["bridge"] = attr.Flags.Has(Bridge)
+		p["tunnel"] = attr.Flags.Has(Tunnel)
+	}
+	if ref, ok := g.EdgeWay(e.EdgeID); ok {
+		p["way_id"] = ref.ID
+	}
+	return p
+}
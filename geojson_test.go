@@ -0,0 +1,53 @@
+package gograph
+
+import (
+	"bytes"
+	"github.com/golang/geo/s2"
+	geojson "github.com/paulmach/go.geojson"
+	"testing"
+)
+
+func TestGraph_WriteGeoJSON(t *testing.T) {
+	g := testGraph()
+	g.Attributes = NewAttributes()
+	g.Attributes.Set(0, EdgeAttributes{Name: "Calle 26", Flags: Bridge})
+	g.SetOSMNode(3, 300)
+
+	buf := &bytes.Buffer{}
+	if err := g.WriteGeoJSON(buf, GeoJSONOptions{Nodes: true}); err != nil {
+		t.Fatal(err)
+	}
+	fc, err := geojson.UnmarshalFeatureCollection(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fc.Features) != 8 {
+		t.Fatalf("expected 4 edges and 4 nodes, got %d features", len(fc.Features))
+	}
+	first := fc.Features[0]
+	if !first.Geometry.IsLineString() || first.Properties["direction"] != "bidirectional" ||
+		first.Properties["name"] != "Calle 26" || first.Properties["bridge"] != true {
+		t.Fatalf("unexpected edge %v", first.Properties)
+	}
+	if lng := first.Geometry.LineString[1][0]; lng < -74.0791 || lng > -74.0789 {
+		t.Fatalf("unexpected geometry %v", first.Geometry.LineString)
+	}
+	oneWay := fc.Features[1]
+	if oneWay.Properties["direction"] != "left_to_right" || oneWay.Properties["weight"] != float64(5) {
+		t.Fatalf("unexpected edge %v", oneWay.Properties)
+	}
+	last := fc.Features[7]
+	if !last.Geometry.IsPoint() || last.Properties["degree"] != float64(2) || last.Properties["osm_id"] != float64(300) {
+		t.Fatalf("unexpected node %v", last.Properties)
+	}
+
+	bounds := s2.RectFromLatLng(s2.LatLngFromDegrees(4.599, -74.0805)).
+		AddPoint(s2.LatLngFromDegrees(4.601, -74.0795))
+	buf.Reset()
+	if err := g.WriteGeoJSON(buf, GeoJSONOptions{Nodes: true, Bounds: &bounds}); err != nil {
+		t.Fatal(err)
+	}
+	if fc, err = geojson.UnmarshalFeatureCollection(buf.Bytes()); err != nil || len(fc.Features) != 3 {
+		t.Fatalf("expected the 2 edges and the node of 0, got %v %v", fc, err)
+	}
+}
@@ -0,0 +1,178 @@
+// Package gpx reads and writes GPX 1.1 files, to use the traces recorded by
+// GPS devices as input of the searches and to show the paths found in the
+// same tools.
+package gpx
+
+import (
+	"encoding/xml"
+	"fmt"
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/golang/geo/s2"
+	"io"
+	"math"
+	"time"
+)
+
+// Point is a point of a trace. Time is zero when the point has no timestamp.
+type Point struct {
+	Lat, Lng     float64
+	Elevation    float64
+	HasElevation bool
+	Time         time.Time
+}
+
+// Trace is a sequence of points, a track segment or a route of a GPX file.
+type Trace struct {
+	Name   string
+	Points []Point
+}
+
+// Coordinates returns the coordinates of the points, to snap them to a graph
+// with ProjectCoordinate.
+func (t Trace) Coordinates() graph.Coordinates {
+	result := make(graph.Coordinates, len(t.Points))
+	for i, p := range t.Points {
+		result[i] = graph.Coordinate{Lat: p.Lat, Lng: p.Lng}
+	}
+	return result
+}
+
+// Options choose how Write writes the traces.
+type Options struct {
+	// Route writes the traces as routes instead of tracks.
+	Route bool
+	// Creator is the program named in the file, gograph when it is empty.
+	Creator string
+}
+
+type file struct {
+	XMLName xml.Name `xml:"gpx"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	Version string   `xml:"version,attr"`
+	Creator string   `xml:"creator,attr"`
+	Tracks  []track  `xml:"trk"`
+	Routes  []route  `xml:"rte"`
+}
+
+type track struct {
+	Name     string    `xml:"name,omitempty"`
+	Segments []segment `xml:"trkseg"`
+}
+
+type segment struct {
+	Points []point `xml:"trkpt"`
+}
+
+type route struct {
+	Name   string  `xml:"name,omitempty"`
+	Points []point `xml:"rtept"`
+}
+
+type point struct {
+	Lat       float64    `xml:"lat,attr"`
+	Lon       float64    `xml:"lon,attr"`
+	Elevation *float64   `xml:"ele"`
+	Time      *time.Time `xml:"time"`
+}
+
+// Read returns the traces of a GPX file: one per track segment, named after
+// its track, followed by one per route. The waypoints are ignored.
+func Read(r io.Reader) ([]Trace, error) {
+	f := file{}
+	if err := xml.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("gpx: %w", err)
+	}
+	traces := make([]Trace, 0)
+	for _, t := range f.Tracks {
+		for _, s := range t.Segments {
+			traces = append(traces, Trace{Name: t.Name, Points: fromGPX(s.Points)})
+		}
+	}
+	for _, r := range f.Routes {
+		traces = append(traces, Trace{Name: r.Name, Points: fromGPX(r.Points)})
+	}
+	return traces, nil
+}
+
+func fromGPX(points []point) []Point {
+	result := make([]Point, len(points))
+	for i, p := range points {
+		result[i] = Point{Lat: p.Lat, Lng: p.Lon}
+		if p.Elevation != nil {
+			result[i].Elevation, result[i].HasElevation = *p.Elevation, true
+		}
+		if p.Time != nil {
+			result[i].Time = *p.Time
+		}
+	}
+	return result
+}
+
+// Write writes the traces to w as a GPX file, each one as a track of a single
+// segment or as a route.
+func Write(w io.Writer, traces []Trace, opts Options) error {
+	f := file{
+		Xmlns:   "http://www.topografix.com/GPX/1/1",
+		Version: "1.1",
+		Creator: opts.Creator,
+	}
+	if f.Creator == "" {
+		f.Creator = "gograph"
+	}
+	for _, t := range traces {
+		if opts.Route {
+			f.Routes = append(f.Routes, route{Name: t.Name, Points: toGPX(t.Points)})
+		} else {
+			f.Tracks = append(f.Tracks, track{Name: t.Name, Segments: []segment{{Points: toGPX(t.Points)}}})
+		}
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(f); err != nil {
+		return fmt.Errorf("gpx: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func toGPX(points []Point) []point {
+	result := make([]point, len(points))
+	for i, p := range points {
+		result[i] = point{Lat: p.Lat, Lon: p.Lng}
+		if p.HasElevation {
+			elevation := p.Elevation
+			result[i].Elevation = &elevation
+		}
+		if !p.Time.IsZero() {
+			t := p.Time.UTC()
+			result[i].Time = &t
+		}
+	}
+	return result
+}
+
+// FromPath returns the trace of the nodes of a path found in n, with their
+// elevation when the path has it. When start is not zero the points get
+// timestamps from the Costs of the path, each unit of cost lasting unit, so
+// a path weighted in seconds uses time.Second. The first point is at start
+// whatever the InitialCost of the search, and the points have no timestamps
+// when the path has no Costs.
+func FromPath(n graph.Network, p graph.Path, start time.Time, unit time.Duration) Trace {
+	t := Trace{Points: make([]Point, len(p.Nodes))}
+	timed := !start.IsZero() && len(p.Costs) == len(p.Nodes)
+	for i, id := range p.Nodes {
+		ll := s2.CellID(n.Node(id).Location).LatLng()
+		t.Points[i] = Point{Lat: ll.Lat.Degrees(), Lng: ll.Lng.Degrees()}
+		if i < len(p.Elevations) && !math.IsNaN(float64(p.Elevations[i])) {
+			t.Points[i].Elevation, t.Points[i].HasElevation = float64(p.Elevations[i]), true
+		}
+		if timed {
+			cost := float64(p.Costs[i] - p.Costs[0])
+			t.Points[i].Time = start.Add(time.Duration(cost * float64(unit)))
+		}
+	}
+	return t
+}
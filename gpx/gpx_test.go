@@ -0,0 +1,98 @@
+package gpx
+
+import (
+	"bytes"
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/golang/geo/s2"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sample = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="device" xmlns="http://www.topografix.com/GPX/1/1">
+  <wpt lat="4.5" lon="-74.1"><name>ignored</name></wpt>
+  <trk>
+    <name>Morning ride</name>
+    <trkseg>
+      <trkpt lat="4.6001" lon="-74.0801"><ele>2600.5</ele><time>2026-10-19T06:00:00Z</time></trkpt>
+      <trkpt lat="4.6002" lon="-74.0791"><time>2026-10-19T06:00:05Z</time></trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="4.6003" lon="-74.0781"></trkpt>
+    </trkseg>
+  </trk>
+  <rte>
+    <name>Planned</name>
+    <rtept lat="4.61" lon="-74.07"></rtept>
+  </rte>
+</gpx>`
+
+func TestRead(t *testing.T) {
+	traces, err := Read(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 3 || traces[1].Name != "Morning ride" || traces[2].Name != "Planned" {
+		t.Fatalf("unexpected traces %v", traces)
+	}
+	first := traces[0].Points[0]
+	if first.Lat != 4.6001 || first.Lng != -74.0801 || !first.HasElevation || first.Elevation != 2600.5 {
+		t.Fatalf("unexpected point %v", first)
+	}
+	if d := traces[0].Points[1].Time.Sub(first.Time); d != 5*time.Second || traces[0].Points[1].HasElevation {
+		t.Fatalf("unexpected point %v", traces[0].Points[1])
+	}
+	if c := traces[0].Coordinates(); len(c) != 2 || c[1] != (graph.Coordinate{Lat: 4.6002, Lng: -74.0791}) {
+		t.Fatalf("unexpected coordinates %v", c)
+	}
+	if _, err := Read(strings.NewReader("<gpx><trk>")); err == nil {
+		t.Fatal("expected an error reading a truncated file")
+	}
+}
+
+func TestFromPath_Write(t *testing.T) {
+	g := graph.Graph{}
+	for i := 0; i < 3; i++ {
+		g.AddNode(graph.Node{Location: uint64(s2.CellIDFromLatLng(s2.LatLngFromDegrees(4.6, -74.08+float64(i)*0.001)))})
+	}
+	g.RelateNodes(g.Nodes[0], g.Nodes[1], 10, graph.Bidirectional)
+	g.RelateNodes(g.Nodes[1], g.Nodes[2], 20, graph.LeftToRight)
+	g.SetEdgeWeight(1, 0, 99)
+	// the timestamps follow the edge the search took, not the lighter one
+	// it avoided.
+	g.AddEdge(0, 1, 1, graph.ClassToll)
+	g.Elevation = []float32{2600, 2610, 2605}
+	p := g.ShortestPath(graph.ShortestPathCriteria{From: 0, To: 2, Avoid: graph.ClassToll, InitialCost: 5})
+
+	start := time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC)
+	trace := FromPath(&g, p, start, time.Second)
+	trace.Name = "Route"
+	for _, route := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		if err := Write(buf, []Trace{trace}, Options{Route: route}); err != nil {
+			t.Fatal(err)
+		}
+		if route != strings.Contains(buf.String(), "<rtept") {
+			t.Fatalf("expected a route %v\n%s", route, buf)
+		}
+		traces, err := Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(traces) != 1 || traces[0].Name != "Route" || len(traces[0].Points) != 3 {
+			t.Fatalf("unexpected traces %v", traces)
+		}
+		first, last := traces[0].Points[0], traces[0].Points[2]
+		if !first.Time.Equal(start) || !last.Time.Equal(start.Add(30*time.Second)) || last.Elevation != 2605 {
+			t.Fatalf("unexpected point %v", last)
+		}
+		if math.Abs(last.Lng+74.078) > 1e-6 || math.Abs(last.Lat-4.6) > 1e-6 {
+			t.Fatalf("unexpected location %v", last)
+		}
+	}
+	if trace := FromPath(&g, p, time.Time{}, time.Second); !trace.Points[1].Time.IsZero() {
+		t.Fatal("expected no timestamps")
+	}
+}
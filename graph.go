@@ -1,9 +1,10 @@
 package gograph
 
 import (
-	"encoding/gob"
+	"bufio"
 	"encoding/json"
 	"github.com/JesseleDuran/gograph/nearest_edge"
+	"github.com/JesseleDuran/gograph/nearest_edge/r2"
 	"github.com/golang/geo/s2"
 	"github.com/umahmood/haversine"
 	"log"
@@ -17,6 +18,18 @@ type Graph struct {
 	IncomingEdges Relations
 	OutgoingEdges Relations
 	EdgeIndex     nearest_edge.Node
+	OSM           OSMRefs
+	// Attributes is optional, it is nil when the graph has no edge attributes.
+	Attributes *Attributes
+	// Elevation holds the elevation in meters of each node, indexed by node ID.
+	// It is nil until sampled with the elevation package. The nodes without
+	// an elevation hold NaN, see NodeElevation.
+	Elevation []float32
+	// Frozen holds the edges once the graph is frozen, and the relations are
+	// then nil. Read the edges with Outgoing and Incoming to support both.
+	Frozen *Frozen
+	// EdgeEnds holds the nodes each edge relates, indexed by EdgeID.
+	EdgeEnds []EdgeKey
 }
 
 // Node also called vertex is the fundamental unit of which graphs are formed.
@@ -29,12 +42,29 @@ type Node struct {
 }
 
 // Edge represents connections between the nodes of a graph.
-// The edges can be directed and weighted.
+// The edges can be directed and weighted. ID is the node the edge relates
+// and EdgeID identifies the edge in the whole graph, the outgoing and the
+// incoming form of an edge share it. EdgeID is stored in the edge, making it
+// 16 bytes, rather than derived from its position in the frozen form: the
+// positions change when the graph is thawed, changed and frozen again while
+// the IDs must not, and the incoming form would need the position anyway.
 type Edge struct {
 	ID     int32
 	Weight float32
+	Class  EdgeClass
+	EdgeID EdgeID
 }
 
+// EdgeClass flags the kinds of road a search can avoid or penalize.
+type EdgeClass uint8
+
+const (
+	ClassToll EdgeClass = 1 << iota
+	ClassFerry
+	ClassUnpaved
+	ClassMotorway
+)
+
 // Relations join the edges of a node, indexed by its ID.
 type Relations [][]Edge
 
@@ -46,24 +76,61 @@ const (
 	RightToLeft
 )
 
+// String returns the name of the direction, as written in the exports.
+func (d EdgeDirection) String() string {
+	switch d {
+	case Bidirectional:
+		return "bidirectional"
+	case LeftToRight:
+		return "left_to_right"
+	case RightToLeft:
+		return "right_to_left"
+	}
+	return "none"
+}
+
+// Node returns the node with the given ID. The nodes of a graph mapped by
+// MapFile are read from the file.
+func (g *Graph) Node(id int32) Node {
+	if g.Frozen != nil && g.Frozen.nodes != nil {
+		return g.Frozen.nodes.node(id)
+	}
+	return g.Nodes[id]
+}
+
 // DegreeNode returns the degree of a directed graph given a node ID. That means
 // that returns the sum of the indegree and the outdegree.
 func (g Graph) DegreeNode(id int32) int {
-	return len(g.IncomingEdges[id]) + len(g.OutgoingEdges[id])
+	return len(g.Incoming(id)) + len(g.Outgoing(id))
+}
+
+// NodeElevation returns the elevation of a node, false when the graph or the
+// node has none.
+func (g Graph) NodeElevation(id int32) (float32, bool) {
+	if int(id) >= len(g.Elevation) {
+		return 0, false
+	}
+	e := g.Elevation[id]
+	return e, !math.IsNaN(float64(e))
 }
 
 // AddNode adds a node to the array of graph nodes, in the position of its id.
+// When the graph has elevations the node gets NaN, as it has none yet.
 func (g *Graph) AddNode(n Node) int32 {
+	g.Thaw()
 	id := len(g.Nodes)
 	n.ID = int32(id)
 	g.Nodes = append(g.Nodes, n)
+	if g.Elevation != nil {
+		g.Elevation = append(g.Elevation, float32(math.NaN()))
+	}
 	g.OutgoingEdges = append(g.OutgoingEdges, make([]Edge, 0))
 	g.IncomingEdges = append(g.IncomingEdges, make([]Edge, 0))
 	return int32(id)
 }
 
 func (g *Graph) DeleteRelations(id int32) {
-
+	g.Thaw()
 	for _, edgeIn := range g.IncomingEdges[id] {
 		result := []Edge{}
 		for _, edgeOut := range g.OutgoingEdges[edgeIn.ID] {
@@ -83,71 +150,137 @@ func (g *Graph) DeleteRelations(id int32) {
 		}
 		g.IncomingEdges[edgeOut.ID] = result
 	}
+	for _, e := range g.IncomingEdges[id] {
+		g.removeEdgeID(e.EdgeID)
+	}
+	for _, e := range g.OutgoingEdges[id] {
+		g.removeEdgeID(e.EdgeID)
+	}
 	g.IncomingEdges[id] = []Edge{}
 	g.OutgoingEdges[id] = []Edge{}
 }
 
 // RelateNodes relates two nodes on a given direction.
 func (g *Graph) RelateNodes(a, b Node, weight float32, dir EdgeDirection) {
+	g.RelateNodesClass(a, b, weight, dir, 0)
+}
+
+// RelateNodesClass relates two nodes on a given direction with edges of the
+// given class.
+func (g *Graph) RelateNodesClass(a, b Node, weight float32, dir EdgeDirection, class EdgeClass) {
 	switch dir {
 
 	case Bidirectional:
 		// relate two nodes bidirectionally o<------>o.
-		{
-			// Left to right relation(relate node n with node x).
-			g.addOutgoingEdge(a.ID, b.ID, weight)
-			g.addIncomingEdge(b.ID, a.ID, weight)
-
-			// Right to left relation(relate node x with node n).
-			g.addOutgoingEdge(b.ID, a.ID, weight)
-			g.addIncomingEdge(a.ID, b.ID, weight)
-		}
+		g.AddEdge(a.ID, b.ID, weight, class)
+		g.AddEdge(b.ID, a.ID, weight, class)
 
 	case LeftToRight:
 		// relate two nodes from left to right o------>o.
-		{
-			g.addOutgoingEdge(a.ID, b.ID, weight)
-			g.addIncomingEdge(a.ID, b.ID, weight)
-		}
+		g.AddEdge(a.ID, b.ID, weight, class)
 
 	case RightToLeft:
 		// relate two nodes from right to left o<------o.
-		{
-			g.addOutgoingEdge(b.ID, a.ID, weight)
-			g.addIncomingEdge(b.ID, a.ID, weight)
-		}
+		g.AddEdge(b.ID, a.ID, weight, class)
 	}
 }
 
+// AddEdge adds the edge from -> to and returns its EdgeID.
+func (g *Graph) AddEdge(from, to int32, weight float32, class EdgeClass) EdgeID {
+	g.Thaw()
+	id := g.newEdgeID(from, to)
+	g.addOutgoingEdge(from, to, weight, class, id)
+	g.addIncomingEdge(from, to, weight, class, id)
+	return id
+}
+
 // addOutgoingEdge Adds an outgoing edge to the given node.
 // An outgoing edge is an edge that leaves a node, for instance:
 // o----->
-func (g *Graph) addOutgoingEdge(from, to int32, weight float32) {
+func (g *Graph) addOutgoingEdge(from, to int32, weight float32, class EdgeClass, id EdgeID) {
 	if g.OutgoingEdges[from] == nil {
 		g.OutgoingEdges[from] = make([]Edge, 0)
 	}
 	g.OutgoingEdges[from] = append(g.OutgoingEdges[from], Edge{
 		ID:     to,
 		Weight: weight,
+		Class:  class,
+		EdgeID: id,
 	})
 }
 
 // addIncomingEdge Adds an incoming edge to the given node.
 // An incoming edge is an edge that enters the node, for instance:
 // ----->o
-func (g *Graph) addIncomingEdge(from, to int32, weight float32) {
+func (g *Graph) addIncomingEdge(from, to int32, weight float32, class EdgeClass, id EdgeID) {
 	if g.IncomingEdges[to] == nil {
 		g.IncomingEdges[to] = make([]Edge, 0)
 	}
 	g.IncomingEdges[to] = append(g.IncomingEdges[to], Edge{
 		ID:     from,
 		Weight: weight,
+		Class:  class,
+		EdgeID: id,
 	})
 }
 
+// RemoveEdge removes an edge from -> to, with its OSM way reference and
+// attributes. It returns false if the edge does not exist. When the nodes are
+// related several times only the first edge is removed, use RemoveEdgeByID to
+// pick one.
+func (g *Graph) RemoveEdge(from, to int32) bool {
+	for _, e := range g.Outgoing(from) {
+		if e.ID == to {
+			return g.RemoveEdgeByID(e.EdgeID)
+		}
+	}
+	return false
+}
+
+// SetEdgeWeight changes the weight of the edge from -> to. When the nodes are
+// related several times all their edges get the weight, use SetEdgeWeightByID
+// to change one.
+func (g *Graph) SetEdgeWeight(from, to int32, weight float32) {
+	g.detach()
+	out := g.Outgoing(from)
+	for i, e := range out {
+		if e.ID == to {
+			out[i].Weight = weight
+		}
+	}
+	in := g.Incoming(to)
+	for i, e := range in {
+		if e.ID == from {
+			in[i].Weight = weight
+		}
+	}
+}
+
+// WeightFunc computes the new weight of the edge from -> to.
+type WeightFunc func(from, to int32, weight float32) float32
+
+// Reweight replaces the weight of every edge by the result of fn.
+// The edges of a graph mapped by MapFile are copied in memory first.
+func (g *Graph) Reweight(fn WeightFunc) {
+	g.detach()
+	for id := range g.Nodes {
+		out := g.Outgoing(int32(id))
+		for i, e := range out {
+			out[i].Weight = fn(int32(id), e.ID, e.Weight)
+		}
+		in := g.Incoming(int32(id))
+		for i, e := range in {
+			in[i].Weight = fn(e.ID, int32(id), e.Weight)
+		}
+	}
+}
+
 // Edges returns the number of edges of the graph.
 func (g Graph) Edges() int {
 	result := 0
+	if g.Frozen != nil {
+		return len(g.Frozen.Incoming.Edges) + len(g.Frozen.Outgoing.Edges)
+	}
 	for _, in := range g.IncomingEdges {
 		result += len(in)
 	}
@@ -158,13 +291,19 @@ func (g Graph) Edges() int {
 	return result
 }
 
+// Serialize writes the graph to a file with WriteTo.
 func (g Graph) Serialize(filePath string) error {
 	file, err := os.Create(filePath)
-	if err == nil {
-		encoder := gob.NewEncoder(file)
-		encoder.Encode(g)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	if _, err = g.WriteTo(w); err == nil {
+		err = w.Flush()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
 	}
-	file.Close()
 	return err
 }
 
@@ -184,8 +323,8 @@ func (g Graph) Degree() float64 {
 func (g Graph) BuildEdgeIndex() nearest_edge.Node {
 	geoSegments := make(nearest_edge.GeoSegments, 0)
 	unique := make(map[int32]map[int32]bool)
-	for i, e := range g.OutgoingEdges {
-		for _, edge := range e {
+	for i := range g.Nodes {
+		for _, edge := range g.Outgoing(int32(i)) {
 			nodeA := g.Nodes[i]
 			nodeB := g.Nodes[edge.ID]
 			A := s2.CellID(g.Nodes[i].Location).LatLng()
@@ -211,17 +350,48 @@ func (g Graph) BuildEdgeIndex() nearest_edge.Node {
 	return nearest_edge.FromGeoSegments(geoSegments...)
 }
 
+// IndexEdge adds the segment between the nodes a and b to the edge index.
+// It returns false when the segment is out of the bounds of the index, which
+// then has to be rebuilt with BuildEdgeIndex.
+func (g *Graph) IndexEdge(a, b int32) bool {
+	segment := g.indexSegment(a, b)
+	if !g.EdgeIndex.Quadrant.Contains(segment.A) || !g.EdgeIndex.Quadrant.Contains(segment.B) {
+		return false
+	}
+	return g.EdgeIndex.Insert(segment)
+}
+
+// UnindexEdge removes the segment between the nodes a and b from the edge index.
+func (g *Graph) UnindexEdge(a, b int32) {
+	g.EdgeIndex.Remove(g.indexSegment(a, b))
+}
+
+// indexSegment returns the segment of the edge index between the nodes a and b.
+func (g Graph) indexSegment(a, b int32) r2.Segment {
+	A := s2.CellID(g.Nodes[a].Location).LatLng()
+	B := s2.CellID(g.Nodes[b].Location).LatLng()
+	return r2.Segment{
+		A: nearest_edge.GeoPointFromCoords(A.Lat.Degrees(), A.Lng.Degrees(), a).ToR2(),
+		B: nearest_edge.GeoPointFromCoords(B.Lat.Degrees(), B.Lng.Degrees(), b).ToR2(),
+	}
+}
+
 func (g Graph) EdgeDirectionByNodes(a, b int32) (EdgeDirection, float32) {
+	return EdgeDirectionByNodes(&g, a, b)
+}
+
+// EdgeDirectionByNodes works as Graph.EdgeDirectionByNodes on any network.
+func EdgeDirectionByNodes(n Network, a, b int32) (EdgeDirection, float32) {
 	toLeft, toRight := false, false
 	weight := float32(0.0)
-	for _, edge := range g.OutgoingEdges[a] {
+	for _, edge := range n.Outgoing(a) {
 		if b == edge.ID {
 			weight = edge.Weight
 			toRight = true
 			break
 		}
 	}
-	for _, edge := range g.OutgoingEdges[b] {
+	for _, edge := range n.Outgoing(b) {
 		if a == edge.ID {
 			weight = edge.Weight
 			toLeft = true
@@ -241,16 +411,16 @@ func (g Graph) EdgeDirectionByNodes(a, b int32) (EdgeDirection, float32) {
 	return -1, weight
 }
 
+// Deserialize reads a graph file with ReadFile. It returns an empty graph if
+// the file can not be read, use ReadFile to get the error.
+//
+// Deprecated: use ReadFile, which returns the error.
 func Deserialize(filePath string) Graph {
-	var g = new(Graph)
-	file, err := os.Open(filePath)
-	if err == nil {
-		decoder := gob.NewDecoder(file)
-		err = decoder.Decode(g)
+	g, err := ReadFile(filePath)
+	if err != nil {
+		log.Println(err)
 	}
-	file.Close()
-	g.EdgeIndex = g.BuildEdgeIndex()
-	return *g
+	return g
 }
 
 // Distance returns the haversine distance in meters between two S2 cell IDs.
@@ -291,10 +461,16 @@ func (g *Graph) NodeAsCompressed(id int32) {
 }
 
 func (g *Graph) ProjectCoordinate(coords Coordinate) (int32, float32) {
-	nearestResult := g.EdgeIndex.GeoQuery(coords.Lat, coords.Lng, []int32{})
+	return SnapToEdge(g, g.EdgeIndex.GeoQuery(coords.Lat, coords.Lng, []int32{}))
+}
+
+// SnapToEdge returns the node a search starts from, or ends at, when the
+// coordinate is projected on the nearest edge, and the distance from the
+// projection to the node.
+func SnapToEdge(n Network, nearestResult nearest_edge.GeoNearestResult) (int32, float32) {
 	tempNode := s2.CellIDFromLatLng(s2.LatLngFromDegrees(nearestResult.Projection.Coordinates[0], nearestResult.Projection.Coordinates[1]))
-	a, b := g.Nodes[nearestResult.Segment.A.ID], g.Nodes[nearestResult.Segment.B.ID]
-	dir, _ := g.EdgeDirectionByNodes(a.ID, b.ID)
+	a, b := n.Node(nearestResult.Segment.A.ID), n.Node(nearestResult.Segment.B.ID)
+	dir, _ := EdgeDirectionByNodes(n, a.ID, b.ID)
 	distanceA := Distance(tempNode, s2.CellID(a.Location))
 	distanceB := Distance(tempNode, s2.CellID(b.Location))
 	switch dir {
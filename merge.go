@@ -0,0 +1,139 @@
+package gograph
+
+// Merge combines two graphs, like the graphs of two neighbor regions built
+// separately, into a new one. The nodes of b that are already in a, by OSM ID
+// when both nodes have one or else by location, are merged with them and
+// their Data is joined. Two nodes with different OSM IDs are never merged. The nodes keep their IDs in a and the nodes only in b
+// follow them. When both graphs relate the same nodes in the same direction
+// the edge of a is kept, each edge of a standing for one edge of b, while the
+// parallel edges of a single graph are all kept. The edges of a keep their
+// EdgeIDs and the edges only in b get new ones after them. The OSM
+// references, attributes and elevations are merged, the nodes of the graph
+// without elevations getting NaN, and the edge index is rebuilt. The graphs
+// given are not changed.
+func Merge(a, b Graph) Graph {
+	g := Graph{Nodes: make([]Node, 0, len(a.Nodes)+len(b.Nodes))}
+	if a.Attributes != nil || b.Attributes != nil {
+		g.Attributes = NewAttributes()
+	}
+	if a.Elevation != nil || b.Elevation != nil {
+		g.Elevation = make([]float32, 0, cap(g.Nodes))
+	}
+	m := merger{
+		g:         &g,
+		locations: make(map[uint64]int32, len(a.Nodes)+len(b.Nodes)),
+		osm:       make(map[int64]int32),
+		edges:     make(map[EdgeKey]int),
+	}
+	ids := [2][]int32{m.addNodes(a, false), m.addNodes(b, true)}
+	m.addEdges(a, ids[0], true)
+	m.addEdges(b, ids[1], false)
+	g.EdgeIndex = g.BuildEdgeIndex()
+	return g
+}
+
+// merger adds the nodes and edges of several graphs to one.
+type merger struct {
+	g *Graph
+	// locations and osm find the nodes added by location and OSM ID.
+	locations map[uint64]int32
+	osm       map[int64]int32
+	// edges counts the edges of the first graph between each pair of nodes
+	// that no edge of the second one was merged with yet.
+	edges map[EdgeKey]int
+}
+
+// addNodes adds the nodes of src, only those not in the graph yet if dedup is
+// set, and returns the ID in the graph of each node of src.
+func (m *merger) addNodes(src Graph, dedup bool) []int32 {
+	ids := make([]int32, len(src.Nodes))
+	for i, n := range src.Nodes {
+		osmID, hasOSM := src.OSMNodeID(int32(i))
+		id, found := int32(0), false
+		if dedup {
+			id, found = m.osm[osmID]
+			if !hasOSM || !found {
+				id, found = m.locations[n.Location]
+				// a node at the same location is another OSM node if both
+				// have an ID.
+				if _, other := m.g.OSMNodeID(id); found && hasOSM && other {
+					found = false
+				}
+			}
+		}
+		if found {
+			node := m.g.Nodes[id]
+			node.Data = joinData(node.Data, n.Data)
+			m.g.Nodes[id] = node
+		} else {
+			id = m.g.AddNode(Node{
+				Data:       append([]uint64(nil), n.Data...),
+				Location:   n.Location,
+				Compressed: n.Compressed,
+			})
+			if _, ok := m.locations[n.Location]; !ok {
+				m.locations[n.Location] = id
+			}
+			if e, ok := src.NodeElevation(int32(i)); ok {
+				m.g.Elevation[id] = e
+			}
+		}
+		if hasOSM {
+			if _, ok := m.osm[osmID]; !ok {
+				m.osm[osmID] = id
+			}
+			if _, ok := m.g.OSMNodeID(id); !ok {
+				m.g.SetOSMNode(id, osmID)
+			}
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+// addEdges adds the edges of src between the nodes given by ids, in the order
+// of their EdgeIDs. The first graph added keeps its IDs, the IDs it does not
+// use being also left unused in the graph, and its edges are counted so the
+// edges of the second one between the same nodes are merged with them.
+func (m *merger) addEdges(src Graph, ids []int32, first bool) {
+	for id := range src.EdgeEnds {
+		from, e, ok := src.EdgeOf(EdgeID(id))
+		if !ok {
+			if first {
+				m.g.EdgeEnds = append(m.g.EdgeEnds, removedEdge)
+			}
+			continue
+		}
+		key := EdgeKey{From: ids[from], To: ids[e.ID]}
+		if first {
+			m.edges[key]++
+		} else if m.edges[key] > 0 {
+			m.edges[key]--
+			continue
+		}
+		edgeID := m.g.AddEdge(key.From, key.To, e.Weight, e.Class)
+		if ref, ok := src.EdgeWay(e.EdgeID); ok {
+			m.g.SetEdgeWay(edgeID, ref)
+		}
+		if attr, ok := src.EdgeAttributes(e.EdgeID); ok {
+			m.g.Attributes.Set(edgeID, attr)
+		}
+	}
+}
+
+// joinData appends the values of b missing in a.
+func joinData(a, b []uint64) []uint64 {
+	for _, v := range b {
+		found := false
+		for _, w := range a {
+			if v == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, v)
+		}
+	}
+	return a
+}
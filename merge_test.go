@@ -0,0 +1,115 @@
+package gograph
+
+import (
+	"github.com/golang/geo/s2"
+	"math"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	a := testGraph()
+	a.SetOSMNode(3, 300)
+	a.Nodes[3].Data = []uint64{1}
+	a.Attributes = NewAttributes()
+	a.Attributes.Set(4, EdgeAttributes{Name: "Calle 26"})
+
+	// b continues the street of a from its node 3, which b knows by OSM ID
+	// with a slightly different location, and repeats the edge 2 -> 3.
+	b := Graph{}
+	for i := 2; i < 6; i++ {
+		b.AddNode(Node{Location: uint64(s2.CellIDFromLatLng(s2.LatLngFromDegrees(4.6, -74.08+float64(i)*0.001)))})
+	}
+	b.Nodes[1].Location++
+	b.Nodes[1].Data = []uint64{1, 2}
+	b.SetOSMNode(1, 300)
+	b.RelateNodes(b.Nodes[0], b.Nodes[1], 9, LeftToRight)
+	b.RelateNodes(b.Nodes[1], b.Nodes[2], 1, Bidirectional)
+	b.RelateNodes(b.Nodes[2], b.Nodes[3], 1, Bidirectional)
+
+	g := Merge(a, b)
+	if len(g.Nodes) != 6 {
+		t.Fatalf("expected 6 nodes, got %d", len(g.Nodes))
+	}
+	if len(g.Nodes[3].Data) != 2 || g.Nodes[3].Location != a.Nodes[3].Location {
+		t.Fatalf("expected the node 3 merged, got %v", g.Nodes[3])
+	}
+	if e, _ := g.EdgeBetween(2, 3); e.Weight != 1 || len(g.Outgoing(2)) != 2 {
+		t.Fatalf("expected the edge of a kept, got %v", g.Outgoing(2))
+	}
+	if attr, ok := g.EdgeAttributes(4); !ok || attr.Name != "Calle 26" {
+		t.Fatal("expected the attributes of a")
+	}
+	if cost := g.Dijkstra(ShortestPathCriteria{From: 0, To: 5}); cost != 5 {
+		t.Fatalf("expected cost 5 across both graphs, got %f", cost)
+	}
+	if cost := g.Dijkstra(ShortestPathCriteria{From: 5, To: 3}); cost != 2 {
+		t.Fatalf("expected cost 2 back to the shared node, got %f", cost)
+	}
+	if id, _ := g.NodeByOSMID(300); id != 3 {
+		t.Fatalf("expected the OSM node 300 at 3, got %d", id)
+	}
+	if len(a.Nodes) != 4 || len(a.Nodes[3].Data) != 1 {
+		t.Fatal("expected a unchanged")
+	}
+}
+
+func TestMerge_ParallelEdges(t *testing.T) {
+	a := testGraph()
+	a.Elevation = []float32{1, 2, 3, 4}
+	a.AddEdge(0, 1, 3, ClassToll)
+
+	// b repeats the nodes 2 and 3 of a, with two edges 2 -> 3, and adds a
+	// node after them. It has no elevations.
+	b := Graph{}
+	for i := 2; i < 5; i++ {
+		b.AddNode(Node{Location: uint64(s2.CellIDFromLatLng(s2.LatLngFromDegrees(4.6, -74.08+float64(i)*0.001)))})
+	}
+	b.AddEdge(0, 1, 1, 0)
+	b.AddEdge(0, 1, 2, ClassToll)
+	b.AddEdge(1, 2, 1, 0)
+
+	g := Merge(a, b)
+	if len(g.Nodes) != 5 {
+		t.Fatalf("expected 5 nodes, got %d", len(g.Nodes))
+	}
+	// the parallel edges of a are kept, and one of the edges 2 -> 3 of b is
+	// merged with the one of a.
+	if out := g.Outgoing(0); len(out) != 3 {
+		t.Fatalf("expected the parallel edges of a kept, got %v", out)
+	}
+	if out := g.Outgoing(2); len(out) != 3 {
+		t.Fatalf("expected the edges 2 -> 3 and 2 -> 1, got %v", out)
+	}
+	if cost := g.Dijkstra(ShortestPathCriteria{From: 2, To: 3}); cost != 1 {
+		t.Fatalf("expected cost 1, got %f", cost)
+	}
+	if len(g.Elevation) != 5 || g.Elevation[3] != 4 || !math.IsNaN(float64(g.Elevation[4])) {
+		t.Fatalf("expected NaN for the elevation missing in b, got %v", g.Elevation)
+	}
+}
+
+func TestMerge_OSMNodes(t *testing.T) {
+	a := testGraph()
+	a.SetOSMNode(3, 300)
+	// the node 0 of b is at the location of the node 3 of a but is another
+	// OSM node, the node 1 has no OSM ID and is merged by location.
+	b := Graph{}
+	b.AddNode(Node{Location: a.Nodes[3].Location})
+	b.AddNode(Node{Location: a.Nodes[2].Location})
+	b.SetOSMNode(0, 400)
+	b.RelateNodes(b.Nodes[0], b.Nodes[1], 1, Bidirectional)
+
+	g := Merge(a, b)
+	if len(g.Nodes) != 5 {
+		t.Fatalf("expected 5 nodes, got %d", len(g.Nodes))
+	}
+	if id, ok := g.NodeByOSMID(300); !ok || id != 3 {
+		t.Fatalf("expected the OSM node 300 at 3, got %d", id)
+	}
+	if id, ok := g.NodeByOSMID(400); !ok || id != 4 {
+		t.Fatalf("expected the OSM node 400 at 4, got %d", id)
+	}
+	if e, ok := g.EdgeBetween(4, 2); !ok || e.Weight != 1 {
+		t.Fatalf("expected the edge of b from its own node, got %v", g.Outgoing(4))
+	}
+}
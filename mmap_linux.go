@@ -0,0 +1,24 @@
+//go:build linux
+
+package gograph
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps a file in memory. The mapping is read only and shared, so its
+// pages are the ones of the page cache.
+func mapFile(file *os.File, size int) ([]byte, error) {
+	if size == 0 {
+		return []byte{}, nil
+	}
+	return syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func unmapFile(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package gograph
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads the file in memory where mmap is not supported.
+func mapFile(file *os.File, size int) ([]byte, error) {
+	data := make([]byte, size)
+	_, err := io.ReadFull(file, data)
+	return data, err
+}
+
+func unmapFile(data []byte) error {
+	return nil
+}
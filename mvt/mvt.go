@@ -0,0 +1,203 @@
+// Package mvt renders the edges of a graph as Mapbox Vector Tiles, to show
+// the graph in a web map tile by tile.
+//
+// The tiles follow the XYZ scheme of the Web Mercator maps. Each edge of the
+// graph inside a tile is a LineString feature of the layer, drawn from its
+// from node to its to node, with its EdgeID as feature ID and the IDs of its
+// nodes, its weight and direction as properties. The edges of a class are
+// flagged with a true toll, ferry, unpaved or motorway property, and the
+// edges with attributes have the highway tag of their way as property.
+//
+// The edges are drawn one by one, not simplified nor merged, so the tiles are
+// meant for the zooms from MinZoom: a tile of a lower zoom holds every edge of
+// a large area, too many for a web map to draw.
+package mvt
+
+import (
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/JesseleDuran/gograph/nearest_edge/mercator"
+	"github.com/JesseleDuran/gograph/nearest_edge/r1"
+	"github.com/JesseleDuran/gograph/nearest_edge/r2"
+	"github.com/golang/geo/s2"
+	"math"
+)
+
+const (
+	// DefaultExtent is the size of a tile in its own coordinates.
+	DefaultExtent = 4096
+	// DefaultBuffer is how far the edges are drawn out of the tile, so the
+	// lines do not end at its border.
+	DefaultBuffer = 64
+	// DefaultLayer is the name of the layer of the edges.
+	DefaultLayer = "edges"
+	// MinZoom is the lowest zoom the tiles are meant for, see the package
+	// documentation.
+	MinZoom = 12
+)
+
+// classProperties are the properties flagging the edges of each class.
+var classProperties = []struct {
+	class graph.EdgeClass
+	key   string
+}{
+	{graph.ClassToll, "toll"},
+	{graph.ClassFerry, "ferry"},
+	{graph.ClassUnpaved, "unpaved"},
+	{graph.ClassMotorway, "motorway"},
+}
+
+// Options configure the tiles. The zero value uses the defaults.
+type Options struct {
+	Extent uint32
+	Buffer uint32
+	Layer  string
+}
+
+func (o Options) withDefaults() Options {
+	if o.Extent == 0 {
+		o.Extent = DefaultExtent
+	}
+	if o.Buffer == 0 {
+		o.Buffer = DefaultBuffer
+	}
+	if o.Layer == "" {
+		o.Layer = DefaultLayer
+	}
+	return o
+}
+
+// maxX is the X of the antimeridian in the projection of the edge index, the
+// index space spans from -maxX to maxX in both axes.
+var maxX = mercator.Projector.FromLatLng(s2.LatLngFromDegrees(0, 180)).X
+
+// TileOf returns the x and y of the tile of a coordinate at the zoom z.
+func TileOf(lat, lng float64, z int) (int, int) {
+	p := r2.PointFromCoordinates(lat, lng, 0)
+	n := float64(int(1) << z)
+	x := int(math.Floor((p.X/maxX + 1) / 2 * n))
+	y := int(math.Floor((1 - p.Y/maxX) / 2 * n))
+	clamp := func(v int) int {
+		return int(math.Max(0, math.Min(n-1, float64(v))))
+	}
+	return clamp(x), clamp(y)
+}
+
+// Encode returns the tile z/x/y with the edges of the graph, found with its
+// edge index. Each edge is a feature, so the two directions of a road and the
+// parallel edges are drawn over each other with their own weight. The edges
+// are clipped to the tile and its buffer. As they are straight lines they are
+// not simplified, an edge whose ends fall on the same tile coordinates is a
+// Point so the roads have no gaps at the low zooms. It returns nil when the
+// tile has no edges. The zooms below MinZoom are encoded too, but make tiles
+// too large to be drawn.
+func Encode(g graph.Graph, z, x, y int, opts Options) []byte {
+	opts = opts.withDefaults()
+	t := newTransform(z, x, y, opts.Extent)
+	buffer := float64(opts.Buffer)
+	low, high := -buffer, float64(opts.Extent)+buffer
+
+	layer := newLayer(opts.Layer, opts.Extent)
+	seen := make(map[graph.EdgeID]bool)
+	for _, s := range g.EdgeIndex.Intersecting(t.rect(low, high)) {
+		ax, ay := t.point(s.A)
+		bx, by := t.point(s.B)
+		ax, ay, bx, by, ok := clip(ax, ay, bx, by, low, high)
+		if !ok {
+			continue
+		}
+		a := [2]int64{int64(math.Round(ax)), int64(math.Round(ay))}
+		b := [2]int64{int64(math.Round(bx)), int64(math.Round(by))}
+		// the segment holds the edges of both directions, the ones from B
+		// are drawn reversed.
+		for _, side := range [2]struct {
+			from, to int32
+			points   [2][2]int64
+		}{{s.A.ID, s.B.ID, [2][2]int64{a, b}}, {s.B.ID, s.A.ID, [2][2]int64{b, a}}} {
+			dir, _ := g.EdgeDirectionByNodes(side.from, side.to)
+			for _, e := range g.Outgoing(side.from) {
+				if e.ID != side.to || seen[e.EdgeID] {
+					continue
+				}
+				seen[e.EdgeID] = true
+				properties := []property{
+					{"from", uint64(side.from)},
+					{"to", uint64(side.to)},
+					{"weight", e.Weight},
+					{"direction", dir.String()},
+				}
+				for _, c := range classProperties {
+					if e.Class&c.class != 0 {
+						properties = append(properties, property{c.key, true})
+					}
+				}
+				if attr, ok := g.EdgeAttributes(e.EdgeID); ok && attr.Highway != "" {
+					properties = append(properties, property{"highway", attr.Highway})
+				}
+				layer.addEdge(uint64(e.EdgeID), side.points, properties)
+			}
+		}
+	}
+	if layer.features == 0 {
+		return nil
+	}
+	return appendMessage(nil, 3, layer.encode())
+}
+
+// transform converts the points of the edge index to the coordinates of a
+// tile.
+type transform struct {
+	// scale is the number of tile units per index unit, and x0 and y0 the
+	// index coordinates of the top left corner of the tile.
+	scale, x0, y0 float64
+}
+
+func newTransform(z, x, y int, extent uint32) transform {
+	n := float64(int(1) << z)
+	tileSize := 2 * maxX / n
+	return transform{
+		scale: float64(extent) / tileSize,
+		x0:    -maxX + float64(x)*tileSize,
+		y0:    maxX - float64(y)*tileSize,
+	}
+}
+
+func (t transform) point(p r2.Point) (float64, float64) {
+	return (p.X - t.x0) * t.scale, (t.y0 - p.Y) * t.scale
+}
+
+// rect returns the index rect of the tile coordinates from low to high.
+func (t transform) rect(low, high float64) r2.Rect {
+	return r2.Rect{
+		X: r1.Interval{Min: t.x0 + low/t.scale, Max: t.x0 + high/t.scale},
+		Y: r1.Interval{Min: t.y0 - high/t.scale, Max: t.y0 - low/t.scale},
+	}
+}
+
+// clip clips the segment a-b to the square from low to high with the
+// Liang-Barsky algorithm. It returns false when the segment is outside.
+func clip(ax, ay, bx, by, low, high float64) (float64, float64, float64, float64, bool) {
+	t0, t1 := 0.0, 1.0
+	dx, dy := bx-ax, by-ay
+	for _, edge := range [4][2]float64{
+		{-dx, ax - low}, {dx, high - ax},
+		{-dy, ay - low}, {dy, high - ay},
+	} {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return 0, 0, 0, 0, false
+			}
+			continue
+		}
+		r := q / p
+		if p < 0 {
+			t0 = math.Max(t0, r)
+		} else {
+			t1 = math.Min(t1, r)
+		}
+		if t0 > t1 {
+			return 0, 0, 0, 0, false
+		}
+	}
+	return ax + t0*dx, ay + t0*dy, ax + t1*dx, ay + t1*dy, true
+}
@@ -0,0 +1,204 @@
+package mvt
+
+import (
+	"encoding/binary"
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/golang/geo/s2"
+	"testing"
+)
+
+// field is a protocol buffer field read by readFields.
+type field struct {
+	num, wire uint64
+	value     uint64
+	data      []byte
+}
+
+func readFields(t *testing.T, b []byte) []field {
+	result := make([]field, 0)
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		b = b[n:]
+		f := field{num: key >> 3, wire: key & 7}
+		switch f.wire {
+		case wireVarint:
+			f.value, n = binary.Uvarint(b)
+			b = b[n:]
+		case wireFixed32:
+			f.value, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			f.data, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			t.Fatalf("unexpected wire type %d", f.wire)
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+func readPacked(b []byte) []uint32 {
+	result := make([]uint32, 0)
+	for len(b) > 0 {
+		v, n := binary.Uvarint(b)
+		result, b = append(result, uint32(v)), b[n:]
+	}
+	return result
+}
+
+func testGraph() graph.Graph {
+	g := graph.Graph{}
+	for i := 0; i < 3; i++ {
+		g.AddNode(graph.Node{Location: uint64(s2.CellIDFromLatLng(s2.LatLngFromDegrees(4.6, -74.08+float64(i)*0.001)))})
+	}
+	g.RelateNodes(g.Nodes[0], g.Nodes[1], 1, graph.Bidirectional)
+	g.RelateNodesClass(g.Nodes[1], g.Nodes[2], 2, graph.LeftToRight, graph.ClassToll)
+	g.EdgeIndex = g.BuildEdgeIndex()
+	return g
+}
+
+// feature is a feature of a tile read by readTile.
+type feature struct {
+	id         uint64
+	geomType   uint64
+	geometry   []uint32
+	properties map[string]field
+}
+
+// readTile returns the features of the layer of a tile, checking the layer.
+func readTile(t *testing.T, b []byte) []feature {
+	tile := readFields(t, b)
+	if len(tile) != 1 || tile[0].num != 3 {
+		t.Fatalf("expected a layer, got %v", tile)
+	}
+	keys, values, features := []string{}, []field{}, [][]field{}
+	name, extent := "", uint64(0)
+	for _, f := range readFields(t, tile[0].data) {
+		switch f.num {
+		case 1:
+			name = string(f.data)
+		case 2:
+			features = append(features, readFields(t, f.data))
+		case 3:
+			keys = append(keys, string(f.data))
+		case 4:
+			values = append(values, readFields(t, f.data)[0])
+		case 5:
+			extent = f.value
+		}
+	}
+	if name != DefaultLayer || extent != DefaultExtent {
+		t.Fatalf("unexpected layer %s %d", name, extent)
+	}
+	result := make([]feature, 0, len(features))
+	for _, fields := range features {
+		f := feature{properties: make(map[string]field)}
+		for _, field := range fields {
+			switch field.num {
+			case 1:
+				f.id = field.value
+			case 2:
+				tags := readPacked(field.data)
+				for i := 0; i < len(tags); i += 2 {
+					f.properties[keys[tags[i]]] = values[tags[i+1]]
+				}
+			case 3:
+				f.geomType = field.value
+			case 4:
+				f.geometry = readPacked(field.data)
+			}
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+func TestEncode(t *testing.T) {
+	g := testGraph()
+	g.Attributes = graph.NewAttributes()
+	g.Attributes.Set(2, graph.EdgeAttributes{Highway: "primary"})
+	x, y := TileOf(4.6, -74.079, 15)
+	if x != 9641 || y != 15964 {
+		t.Fatalf("unexpected tile %d/%d", x, y)
+	}
+	features := readTile(t, Encode(g, 15, x, y, Options{}))
+	if len(features) != 3 {
+		t.Fatalf("expected a feature per edge, got %d", len(features))
+	}
+
+	// the features follow the edge index, find them by EdgeID.
+	byID := make(map[uint64]feature)
+	for _, f := range features {
+		if f.geomType != geomLineString || len(f.geometry) != 6 || f.geometry[0] != command(commandMoveTo, 1) || f.geometry[3] != command(commandLineTo, 1) {
+			t.Fatalf("unexpected geometry %v", f.geometry)
+		}
+		for _, v := range f.geometry[1:3] {
+			if x := int64(v>>1) ^ -int64(v&1); x < -DefaultBuffer || x > DefaultExtent+DefaultBuffer {
+				t.Fatalf("expected the line clipped, got %v", f.geometry)
+			}
+		}
+		byID[f.id] = f
+	}
+	toll := byID[2].properties
+	if toll["toll"].num != 7 || toll["toll"].value != 1 || string(toll["highway"].data) != "primary" ||
+		string(toll["direction"].data) != "left_to_right" || toll["weight"].wire != wireFixed32 {
+		t.Fatalf("unexpected properties %v", toll)
+	}
+	// the edge 1 -> 0 is drawn from the node 1, westwards.
+	forward, backward := byID[0], byID[1]
+	_, isToll := backward.properties["toll"]
+	_, highway := backward.properties["highway"]
+	if backward.properties["from"].value != 1 || string(backward.properties["direction"].data) != "bidirectional" || isToll || highway {
+		t.Fatalf("unexpected properties %v", backward.properties)
+	}
+	if forward.geometry[4]&1 != 0 || backward.geometry[4]&1 != 1 {
+		t.Fatalf("expected the edges drawn in their direction, got %v and %v", forward.geometry, backward.geometry)
+	}
+}
+
+func TestEncode_Empty(t *testing.T) {
+	g := testGraph()
+	if tile := Encode(g, 15, 0, 0, Options{}); tile != nil {
+		t.Fatalf("expected no tile far from the graph, got %d bytes", len(tile))
+	}
+	// the segments of the index without edges are not drawn.
+	g.RemoveEdgeByID(2)
+	x, y := TileOf(4.6, -74.079, 15)
+	if features := readTile(t, Encode(g, 15, x, y, Options{})); len(features) != 2 {
+		t.Fatalf("expected the features of the edges 0 and 1, got %d", len(features))
+	}
+}
+
+func TestEncode_LowZoom(t *testing.T) {
+	g := testGraph()
+	// at zoom 5 the edges are shorter than a tile unit, the ones with both
+	// ends rounded to the same coordinates are points.
+	x, y := TileOf(4.6, -74.079, 5)
+	features := readTile(t, Encode(g, 5, x, y, Options{}))
+	if len(features) != 3 {
+		t.Fatalf("expected the short edges kept, got %d features", len(features))
+	}
+	points := 0
+	for _, f := range features {
+		switch {
+		case f.geomType == geomPoint && len(f.geometry) == 3:
+			points++
+		case f.geomType == geomLineString && len(f.geometry) == 6 && f.geometry[4] <= 2 && f.geometry[5] == 0:
+		default:
+			t.Fatalf("unexpected geometry %d %v", f.geomType, f.geometry)
+		}
+	}
+	if points == 0 {
+		t.Fatal("expected an edge drawn as a point")
+	}
+}
+
+func TestClip(t *testing.T) {
+	ax, ay, bx, by, ok := clip(-10, 50, 110, 50, 0, 100)
+	if !ok || ax != 0 || ay != 50 || bx != 100 || by != 50 {
+		t.Fatalf("unexpected clip %f %f %f %f", ax, ay, bx, by)
+	}
+	if _, _, _, _, ok := clip(-10, -10, -5, 200, 0, 100); ok {
+		t.Fatal("expected the segment outside")
+	}
+}
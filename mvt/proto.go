@@ -0,0 +1,158 @@
+package mvt
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The tiles are protocol buffers of the vector tile specification 2.1, they
+// are written by hand as the format only needs a few field types.
+const (
+	wireVarint  = 0
+	wireBytes   = 2
+	wireFixed32 = 5
+
+	geomPoint      = 1
+	geomLineString = 2
+	commandMoveTo  = 1
+	commandLineTo  = 2
+)
+
+// property is a key and a value of a feature, the value is a string, uint64,
+// float32 or bool.
+type property struct {
+	key   string
+	value interface{}
+}
+
+// layer accumulates the features of a tile layer, sharing the keys and
+// values of their properties.
+type layer struct {
+	name     string
+	extent   uint32
+	keys     map[string]uint32
+	values   map[interface{}]uint32
+	encoded  []byte
+	features int
+	// keyList and valueList keep the order of the keys and values.
+	keyList   []string
+	valueList []interface{}
+}
+
+func newLayer(name string, extent uint32) *layer {
+	return &layer{
+		name:   name,
+		extent: extent,
+		keys:   make(map[string]uint32),
+		values: make(map[interface{}]uint32),
+	}
+}
+
+// addEdge adds a LineString feature of two points, or a Point feature when
+// both are the same as a line of length zero is not valid.
+func (l *layer) addEdge(id uint64, points [2][2]int64, properties []property) {
+	tags := make([]uint32, 0, 2*len(properties))
+	for _, p := range properties {
+		tags = append(tags, l.key(p.key), l.value(p.value))
+	}
+	geomType := uint64(geomPoint)
+	geometry := []uint32{command(commandMoveTo, 1), zigzag(points[0][0]), zigzag(points[0][1])}
+	if points[0] != points[1] {
+		geomType = geomLineString
+		geometry = append(geometry, command(commandLineTo, 1), zigzag(points[1][0]-points[0][0]), zigzag(points[1][1]-points[0][1]))
+	}
+	feature := appendVarintField(nil, 1, id)
+	feature = appendPacked(feature, 2, tags)
+	feature = appendVarintField(feature, 3, geomType)
+	feature = appendPacked(feature, 4, geometry)
+	l.encoded = appendMessage(l.encoded, 2, feature)
+	l.features++
+}
+
+func (l *layer) key(k string) uint32 {
+	i, ok := l.keys[k]
+	if !ok {
+		i = uint32(len(l.keyList))
+		l.keys[k] = i
+		l.keyList = append(l.keyList, k)
+	}
+	return i
+}
+
+func (l *layer) value(v interface{}) uint32 {
+	i, ok := l.values[v]
+	if !ok {
+		i = uint32(len(l.valueList))
+		l.values[v] = i
+		l.valueList = append(l.valueList, v)
+	}
+	return i
+}
+
+// encode returns the layer message.
+func (l *layer) encode() []byte {
+	b := appendVarintField(nil, 15, 2)
+	b = appendMessage(b, 1, []byte(l.name))
+	b = append(b, l.encoded...)
+	for _, k := range l.keyList {
+		b = appendMessage(b, 3, []byte(k))
+	}
+	for _, v := range l.valueList {
+		b = appendMessage(b, 4, encodeValue(v))
+	}
+	return appendVarintField(b, 5, uint64(l.extent))
+}
+
+func encodeValue(v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return appendMessage(nil, 1, []byte(v))
+	case float32:
+		b := appendKey(nil, 2, wireFixed32)
+		b = append(b, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(b[len(b)-4:], math.Float32bits(v))
+		return b
+	case uint64:
+		return appendVarintField(nil, 5, v)
+	case bool:
+		if v {
+			return appendVarintField(nil, 7, 1)
+		}
+		return appendVarintField(nil, 7, 0)
+	}
+	return nil
+}
+
+func command(id, count uint32) uint32 {
+	return id&0x7 | count<<3
+}
+
+func zigzag(v int64) uint32 {
+	return uint32((v << 1) ^ (v >> 63))
+}
+
+func appendKey(b []byte, field, wire uint64) []byte {
+	return appendUvarint(b, field<<3|wire)
+}
+
+func appendVarintField(b []byte, field, v uint64) []byte {
+	return appendUvarint(appendKey(b, field, wireVarint), v)
+}
+
+func appendMessage(b []byte, field uint64, msg []byte) []byte {
+	b = appendUvarint(appendKey(b, field, wireBytes), uint64(len(msg)))
+	return append(b, msg...)
+}
+
+func appendPacked(b []byte, field uint64, values []uint32) []byte {
+	packed := make([]byte, 0, len(values)*2)
+	for _, v := range values {
+		packed = appendUvarint(packed, uint64(v))
+	}
+	return appendMessage(b, field, packed)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(b, buf[:binary.PutUvarint(buf, v)]...)
+}
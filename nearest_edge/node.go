@@ -86,6 +86,33 @@ func (n *Node) Insert(segment r2.Segment) bool {
 	return true
 }
 
+// Remove deletes the segments that join the points with the IDs of the
+// given segment, in any order. It returns whether any segment was removed.
+func (n *Node) Remove(segment r2.Segment) bool {
+	if !n.Quadrant.Intercepts(segment.BoundingBox()) {
+		return false
+	}
+	removed := false
+	if n.isLeaf() {
+		result := n.Segments[:0]
+		for _, e := range n.Segments {
+			if (e.A.ID == segment.A.ID && e.B.ID == segment.B.ID) || (e.A.ID == segment.B.ID && e.B.ID == segment.A.ID) {
+				removed = true
+				continue
+			}
+			result = append(result, e)
+		}
+		n.Segments = result
+		return removed
+	}
+	for _, c := range n.Children {
+		if c != nil && c.Remove(segment) {
+			removed = true
+		}
+	}
+	return removed
+}
+
 // rebalance a node to find space for a given segment.
 // the rebalancing process consists in add the node segment + the given
 // segment on any of its children.
@@ -160,6 +187,32 @@ func Range(branch Branch, ignore map[int32]bool, circle *r2.Circle) NearestResul
 	return result
 }
 
+// Intersecting returns the segments whose bounding box intercepts the rect,
+// each one once although it can be in several quadrants.
+func (n Node) Intersecting(rect r2.Rect) r2.Segments {
+	result := make(r2.Segments, 0)
+	n.intersecting(rect, make(map[[2]int32]bool), &result)
+	return result
+}
+
+func (n Node) intersecting(rect r2.Rect, seen map[[2]int32]bool, result *r2.Segments) {
+	if !n.Quadrant.Intercepts(rect) {
+		return
+	}
+	for _, e := range n.Segments {
+		key := [2]int32{e.A.ID, e.B.ID}
+		if !seen[key] && e.BoundingBox().Intercepts(rect) {
+			seen[key] = true
+			*result = append(*result, e)
+		}
+	}
+	for _, c := range n.Children {
+		if c != nil {
+			c.intersecting(rect, seen, result)
+		}
+	}
+}
+
 func (n *Node) FindOrCreateChild(rect r2.Rect, id int) *Node {
 	if n.Children[id] == nil {
 		n.Children[id] = &Node{Quadrant: rect, Depth: n.Depth + 1}
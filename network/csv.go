@@ -0,0 +1,122 @@
+package network
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/golang/geo/s2"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadCSV builds a graph from a CSV list of nodes and a CSV list of edges,
+// both with a header naming their columns, in any order and case.
+//
+// The nodes have the columns id, lat and lng (or lon). The edges have the
+// columns from and to, with the ids of their nodes, and optionally weight
+// and direction, see ParseDirection. An edge without weight weighs its
+// length in meters. Other columns are ignored.
+//
+// The nodes are numbered in the order of the file, and the ids of the file
+// are returned indexed by node ID.
+func ReadCSV(nodes, edges io.Reader) (graph.Graph, []string, error) {
+	g := graph.Graph{}
+	ids := make([]string, 0)
+	byID := make(map[string]int32)
+
+	err := readCSV(nodes, "nodes", []string{"id", "lat", "lng"}, func(row map[string]string) error {
+		id := row["id"]
+		if _, ok := byID[id]; ok {
+			return fmt.Errorf("duplicated node %q", id)
+		}
+		lat, err := strconv.ParseFloat(row["lat"], 64)
+		if err != nil {
+			return err
+		}
+		lng, err := strconv.ParseFloat(row["lng"], 64)
+		if err != nil {
+			return err
+		}
+		location := s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng))
+		byID[id] = g.AddNode(graph.Node{Location: uint64(location)})
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		return graph.Graph{}, nil, err
+	}
+
+	err = readCSV(edges, "edges", []string{"from", "to"}, func(row map[string]string) error {
+		from, ok := byID[row["from"]]
+		if !ok {
+			return fmt.Errorf("unknown node %q", row["from"])
+		}
+		to, ok := byID[row["to"]]
+		if !ok {
+			return fmt.Errorf("unknown node %q", row["to"])
+		}
+		dir, err := ParseDirection(row["direction"])
+		if err != nil {
+			return err
+		}
+		weight := graph.Distance(s2.CellID(g.Nodes[from].Location), s2.CellID(g.Nodes[to].Location))
+		if w := row["weight"]; w != "" {
+			parsed, err := parseWeight(w)
+			if err != nil {
+				return err
+			}
+			weight = float32(parsed)
+		}
+		g.RelateNodes(g.Nodes[from], g.Nodes[to], weight, dir)
+		return nil
+	})
+	if err != nil {
+		return graph.Graph{}, nil, err
+	}
+	g.EdgeIndex = g.BuildEdgeIndex()
+	return g, ids, nil
+}
+
+// readCSV calls fn with each row of a CSV file by column name, checking the
+// file has the required columns.
+func readCSV(r io.Reader, name string, required []string, fn func(map[string]string) error) error {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("network: reading the header of the %s: %w", name, err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+		if header[i] == "lon" {
+			header[i] = "lng"
+		}
+	}
+	for _, column := range required {
+		found := false
+		for _, h := range header {
+			found = found || h == column
+		}
+		if !found {
+			return fmt.Errorf("network: the %s have no %s column", name, column)
+		}
+	}
+	row := make(map[string]string, len(header))
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("network: reading the %s: %w", name, err)
+		}
+		for i, h := range header {
+			row[h] = strings.TrimSpace(record[i])
+		}
+		if err := fn(row); err != nil {
+			return fmt.Errorf("network: %s line %d: %w", name, line, err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package network
+
+import (
+	"fmt"
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/golang/geo/s2"
+	geojson "github.com/paulmach/go.geojson"
+	"io"
+	"math"
+	"sort"
+)
+
+// GeoJSONOptions configure ReadGeoJSON.
+type GeoJSONOptions struct {
+	// DirectionProperty is the property with the direction of each line, see
+	// ParseDirection. It is "direction" when empty, the lines without it are
+	// bidirectional.
+	DirectionProperty string
+	// WeightProperty is the property with the weight of each line, which is
+	// split among its edges by their length. The lines without it weigh their
+	// length in meters.
+	WeightProperty string
+	// Intersections also nodes the lines where they cross, not only at the
+	// vertices they share. The lines that overlap are not noded.
+	Intersections bool
+}
+
+// line is a LineString of the file with the values of its feature.
+type line struct {
+	// points are [lng, lat] coordinates.
+	points [][]float64
+	dir    graph.EdgeDirection
+	// perMeter is the weight of each meter of the line, when weighted is set
+	// as its feature has a weight property.
+	perMeter float64
+	weighted bool
+}
+
+// ReadGeoJSON builds a graph from the LineStrings and MultiLineStrings of a
+// GeoJSON FeatureCollection. Each vertex is a node, the vertices at the same
+// location being the same node, and consecutive vertices are related with
+// RelateNodes in the direction of their line. Other geometries are ignored.
+func ReadGeoJSON(r io.Reader, opts GeoJSONOptions) (graph.Graph, error) {
+	if opts.DirectionProperty == "" {
+		opts.DirectionProperty = "direction"
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return graph.Graph{}, err
+	}
+	fc, err := geojson.UnmarshalFeatureCollection(data)
+	if err != nil {
+		return graph.Graph{}, fmt.Errorf("network: %w", err)
+	}
+
+	lines := make([]line, 0)
+	for i, f := range fc.Features {
+		if f.Geometry == nil {
+			continue
+		}
+		var parts [][][]float64
+		switch {
+		case f.Geometry.IsLineString():
+			parts = [][][]float64{f.Geometry.LineString}
+		case f.Geometry.IsMultiLineString():
+			parts = f.Geometry.MultiLineString
+		default:
+			continue
+		}
+		for _, points := range parts {
+			for _, p := range points {
+				if len(p) < 2 {
+					return graph.Graph{}, fmt.Errorf("network: position with %d numbers in feature %d", len(p), i)
+				}
+			}
+		}
+		l := line{}
+		if v, ok := f.Properties[opts.DirectionProperty]; ok && v != nil {
+			if l.dir, err = ParseDirection(fmt.Sprint(v)); err != nil {
+				return graph.Graph{}, fmt.Errorf("%w in feature %d", err, i)
+			}
+		}
+		if v, ok := f.Properties[opts.WeightProperty]; ok && v != nil && opts.WeightProperty != "" {
+			weight, err := parseWeight(fmt.Sprint(v))
+			if err != nil {
+				return graph.Graph{}, fmt.Errorf("network: invalid weight in feature %d: %w", i, err)
+			}
+			total := float64(0)
+			for _, points := range parts {
+				for j := 1; j < len(points); j++ {
+					total += float64(distance(points[j-1], points[j]))
+				}
+			}
+			if total > 0 {
+				l.perMeter, l.weighted = weight/total, true
+			}
+		}
+		for _, points := range parts {
+			l.points = points
+			lines = append(lines, l)
+		}
+	}
+	if opts.Intersections {
+		splitIntersections(lines)
+	}
+
+	g := graph.Graph{}
+	nodes := make(map[s2.CellID]int32)
+	node := func(p []float64) int32 {
+		location := s2.CellIDFromLatLng(s2.LatLngFromDegrees(p[1], p[0]))
+		id, ok := nodes[location]
+		if !ok {
+			id = g.AddNode(graph.Node{Location: uint64(location)})
+			nodes[location] = id
+		}
+		return id
+	}
+	for _, l := range lines {
+		for i := 1; i < len(l.points); i++ {
+			from, to := node(l.points[i-1]), node(l.points[i])
+			if from == to {
+				continue
+			}
+			weight := distance(l.points[i-1], l.points[i])
+			if l.weighted {
+				weight = float32(l.perMeter * float64(weight))
+			}
+			g.RelateNodes(g.Nodes[from], g.Nodes[to], weight, l.dir)
+		}
+	}
+	g.EdgeIndex = g.BuildEdgeIndex()
+	return g, nil
+}
+
+// distance returns the meters between two [lng, lat] coordinates.
+func distance(a, b []float64) float32 {
+	return graph.Distance(
+		s2.CellIDFromLatLng(s2.LatLngFromDegrees(a[1], a[0])),
+		s2.CellIDFromLatLng(s2.LatLngFromDegrees(b[1], b[0])),
+	)
+}
+
+// segment is a segment of a line, for splitIntersections.
+type segment struct {
+	line, index int
+	a, b        []float64
+}
+
+// cut is a point where a segment is split, at the fraction t of its length.
+type cut struct {
+	t     float64
+	point []float64
+}
+
+// splitIntersections adds a vertex to the lines where they cross. The
+// segments are swept by their west end, so only the segments that overlap in
+// longitude are compared.
+func splitIntersections(lines []line) {
+	segments := make([]segment, 0)
+	for i, l := range lines {
+		for j := 1; j < len(l.points); j++ {
+			segments = append(segments, segment{line: i, index: j - 1, a: l.points[j-1], b: l.points[j]})
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return math.Min(segments[i].a[0], segments[i].b[0]) < math.Min(segments[j].a[0], segments[j].b[0])
+	})
+	cuts := make(map[[2]int][]cut)
+	for i, s := range segments {
+		east := math.Max(s.a[0], s.b[0])
+		for _, o := range segments[i+1:] {
+			if math.Min(o.a[0], o.b[0]) > east {
+				break
+			}
+			if s.line == o.line && (s.index-o.index == 1 || o.index-s.index == 1) {
+				continue
+			}
+			ts, to, ok := intersect(s.a, s.b, o.a, o.b)
+			if !ok {
+				continue
+			}
+			// at the end of a segment the vertex is used as is, so both lines
+			// get the same node.
+			p := []float64{s.a[0] + ts*(s.b[0]-s.a[0]), s.a[1] + ts*(s.b[1]-s.a[1])}
+			switch {
+			case ts == 0:
+				p = s.a
+			case ts == 1:
+				p = s.b
+			case to == 0:
+				p = o.a
+			case to == 1:
+				p = o.b
+			}
+			if ts > 0 && ts < 1 {
+				key := [2]int{s.line, s.index}
+				cuts[key] = append(cuts[key], cut{t: ts, point: p})
+			}
+			if to > 0 && to < 1 {
+				key := [2]int{o.line, o.index}
+				cuts[key] = append(cuts[key], cut{t: to, point: p})
+			}
+		}
+	}
+	for i := range lines {
+		points := make([][]float64, 0, len(lines[i].points))
+		for j, p := range lines[i].points {
+			if j > 0 {
+				c := cuts[[2]int{i, j - 1}]
+				sort.Slice(c, func(a, b int) bool { return c[a].t < c[b].t })
+				for _, cut := range c {
+					points = append(points, cut.point)
+				}
+			}
+			points = append(points, p)
+		}
+		lines[i].points = points
+	}
+}
+
+// intersect returns where the segments a-b and c-d cross, as the fractions
+// of their length, and false if they do not cross or are parallel.
+func intersect(a, b, c, d []float64) (float64, float64, bool) {
+	rx, ry := b[0]-a[0], b[1]-a[1]
+	sx, sy := d[0]-c[0], d[1]-c[1]
+	denominator := rx*sy - ry*sx
+	if denominator == 0 {
+		return 0, 0, false
+	}
+	qx, qy := c[0]-a[0], c[1]-a[1]
+	t := (qx*sy - qy*sx) / denominator
+	u := (qx*ry - qy*rx) / denominator
+	if t < 0 || t > 1 || u < 0 || u > 1 {
+		return 0, 0, false
+	}
+	return t, u, true
+}
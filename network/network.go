@@ -0,0 +1,42 @@
+// Package network builds graphs from network files that do not come from
+// OpenStreetMap, like the roads of a campus or the corridors of a building:
+// node and edge lists in CSV, and GeoJSON LineStrings.
+package network
+
+import (
+	"fmt"
+	graph "github.com/JesseleDuran/gograph"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseDirection reads the direction of an edge or line from a file. The
+// empty value, "both", "bidirectional", "no", "false" and "0" relate the nodes
+// both ways; "forward", "left_to_right", "oneway", "yes", "true" and "1" from
+// the first node to the second; "backward", "right_to_left", "reverse" and
+// "-1" from the second to the first.
+func ParseDirection(s string) (graph.EdgeDirection, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "both", "bidirectional", "no", "false", "0":
+		return graph.Bidirectional, nil
+	case "forward", "left_to_right", "oneway", "yes", "true", "1":
+		return graph.LeftToRight, nil
+	case "backward", "right_to_left", "reverse", "-1":
+		return graph.RightToLeft, nil
+	}
+	return -1, fmt.Errorf("network: unknown direction %q", s)
+}
+
+// parseWeight reads the weight of an edge or line from a file. It must be a
+// finite number and not negative, as the searches require.
+func parseWeight(s string) (float64, error) {
+	weight, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if weight < 0 || math.IsNaN(weight) || weight > math.MaxFloat32 {
+		return 0, fmt.Errorf("weight %q is negative or not finite", s)
+	}
+	return weight, nil
+}
@@ -0,0 +1,103 @@
+package network
+
+import (
+	graph "github.com/JesseleDuran/gograph"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	nodes := `id,Lat,Lon,name
+a,4.6,-74.08,gate
+b,4.6,-74.079,
+c,4.601,-74.079,lab
+`
+	edges := `from,to,weight,direction
+a,b,10,
+b,c,,forward
+c,a,3,backward
+`
+	g, ids, err := ReadCSV(strings.NewReader(nodes), strings.NewReader(edges))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Nodes) != 3 || len(ids) != 3 || ids[2] != "c" {
+		t.Fatalf("unexpected nodes %v %v", g.Nodes, ids)
+	}
+	if e, ok := g.EdgeBetween(1, 0); !ok || e.Weight != 10 {
+		t.Fatalf("expected the edge b -> a, got %v", g.Outgoing(1))
+	}
+	if e, ok := g.EdgeBetween(1, 2); !ok || e.Weight < 110 || e.Weight > 112 {
+		t.Fatalf("expected the edge b -> c weighted by its length, got %v", e)
+	}
+	if _, ok := g.EdgeBetween(2, 1); ok {
+		t.Fatal("expected the edge b -> c one way")
+	}
+	if e, ok := g.EdgeBetween(0, 2); !ok || e.Weight != 3 {
+		t.Fatalf("expected the edge a -> c, got %v", g.Outgoing(0))
+	}
+
+	for _, c := range []struct{ nodes, edges, err string }{
+		{"id,lat\na,1\n", "from,to\n", "no lng column"},
+		{nodes, "from,to\na,x\n", `edges line 2: unknown node "x"`},
+		{nodes, "from,to,direction\na,b,sideways\n", "unknown direction"},
+		{nodes + "a,1,1,\n", "from,to\n", `nodes line 5: duplicated node "a"`},
+		{nodes, "from,to,weight\na,b,-5\n", `edges line 2: weight "-5" is negative`},
+		{nodes, "from,to,weight\na,b,NaN\n", `weight "NaN" is negative or not finite`},
+		{nodes, "from,to,weight\na,b,Inf\n", `weight "Inf" is negative or not finite`},
+		{nodes, "from,to,weight\na,b,1e300\n", `weight "1e300" is negative or not finite`},
+	} {
+		_, _, err := ReadCSV(strings.NewReader(c.nodes), strings.NewReader(c.edges))
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("expected an error with %q, got %v", c.err, err)
+		}
+	}
+}
+
+func TestReadGeoJSON(t *testing.T) {
+	// a street from west to east, a one way street crossing it from south to
+	// north, and a street ending on the first one.
+	data := `{"type":"FeatureCollection","features":[
+{"type":"Feature","properties":{"cost":20},"geometry":{"type":"LineString","coordinates":[[-74.08,4.6],[-74.078,4.6]]}},
+{"type":"Feature","properties":{"oneway":"yes"},"geometry":{"type":"LineString","coordinates":[[-74.079,4.599],[-74.079,4.601]]}},
+{"type":"Feature","properties":{},"geometry":{"type":"MultiLineString","coordinates":[[[-74.0785,4.602],[-74.0785,4.6]]]}},
+{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[-74.0,4.0]}}
+]}`
+	g, err := ReadGeoJSON(strings.NewReader(data), GeoJSONOptions{DirectionProperty: "oneway", WeightProperty: "cost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Nodes) != 6 || g.Edges() != 10 {
+		t.Fatalf("expected the lines apart, got %d nodes and %d edges", len(g.Nodes), g.Edges())
+	}
+
+	g, err = ReadGeoJSON(strings.NewReader(data), GeoJSONOptions{DirectionProperty: "oneway", WeightProperty: "cost", Intersections: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Nodes) != 7 {
+		t.Fatalf("expected the crossing and the end noded, got %d nodes", len(g.Nodes))
+	}
+	// the west end, the crossing, the T junction and the east end of the
+	// first street, with its weight split by length.
+	if cost := g.Dijkstra(graph.ShortestPathCriteria{From: 0, To: 3}); cost < 19.99 || cost > 20.01 {
+		t.Fatalf("expected the weight of the street, got %f", cost)
+	}
+	south, north := int32(4), int32(5)
+	if cost := g.Dijkstra(graph.ShortestPathCriteria{From: south, To: north}); cost == graph.INFINITE {
+		t.Fatal("expected a path along the one way street")
+	}
+	if cost := g.Dijkstra(graph.ShortestPathCriteria{From: north, To: south}); cost != graph.INFINITE {
+		t.Fatalf("expected no way back against the one way street, got %f", cost)
+	}
+	for _, c := range []struct{ feature, err string }{
+		{`{"type":"Feature","properties":{"direction":"up"},"geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]}}`, "unknown direction"},
+		{`{"type":"Feature","properties":{},"geometry":{"type":"LineString","coordinates":[[1],[2]]}}`, "position with 1 numbers in feature 0"},
+		{`{"type":"Feature","properties":{"cost":-5},"geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]}}`, "invalid weight in feature 0"},
+	} {
+		_, err := ReadGeoJSON(strings.NewReader(`{"type":"FeatureCollection","features":[`+c.feature+`]}`), GeoJSONOptions{WeightProperty: "cost"})
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("expected an error with %q, got %v", c.err, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package gograph
+
+// OSMRefs links the graph with the OpenStreetMap data it was built from.
+type OSMRefs struct {
+	// Nodes holds the OSM node ID of each graph node, indexed by graph node ID.
+	Nodes []int64
+	// NodeIDs is the inverse of Nodes, from OSM node ID to graph node ID.
+	NodeIDs map[int64]int32
+	// Ways holds the OSM way each edge comes from, indexed by EdgeID, so
+	// parallel edges from different ways keep their own reference. The edges
+	// without a way have the zero WayRef.
+	Ways []WayRef
+}
+
+// EdgeKey identifies a directed edge by the nodes it relates.
+type EdgeKey struct {
+	From, To int32
+}
+
+// WayRef locates an edge inside an OSM way. Position is the index of the way
+// segment, so the edge relates the way nodes at Position and Position+1.
+// Reverse is set when the edge goes against the order of the way nodes.
+type WayRef struct {
+	ID       int64
+	Position int32
+	Reverse  bool
+}
+
+// SetOSMNode links a graph node with its OSM node ID.
+func (g *Graph) SetOSMNode(id int32, osmID int64) {
+	for int(id) >= len(g.OSM.Nodes) {
+		g.OSM.Nodes = append(g.OSM.Nodes, 0)
+	}
+	if g.OSM.NodeIDs == nil {
+		g.OSM.NodeIDs = make(map[int64]int32)
+	}
+	g.OSM.Nodes[id] = osmID
+	g.OSM.NodeIDs[osmID] = id
+}
+
+// OSMNodeID returns the OSM node ID of a graph node.
+func (g Graph) OSMNodeID(id int32) (int64, bool) {
+	if id < 0 || int(id) >= len(g.OSM.Nodes) || g.OSM.Nodes[id] == 0 {
+		return 0, false
+	}
+	return g.OSM.Nodes[id], true
+}
+
+// NodeByOSMID returns the graph node ID of an OSM node.
+func (g Graph) NodeByOSMID(osmID int64) (int32, bool) {
+	id, ok := g.OSM.NodeIDs[osmID]
+	return id, ok
+}
+
+// SetEdgeWay links an edge with the OSM way it comes from, the zero WayRef
+// removes the link.
+func (g *Graph) SetEdgeWay(id EdgeID, ref WayRef) {
+	if int(id) >= len(g.OSM.Ways) {
+		if ref == (WayRef{}) {
+			return
+		}
+		g.OSM.Ways = append(g.OSM.Ways, make([]WayRef, int(id)+1-len(g.OSM.Ways))...)
+	}
+	g.OSM.Ways[id] = ref
+}
+
+// EdgeWay returns the OSM way an edge comes from.
+func (g Graph) EdgeWay(id EdgeID) (WayRef, bool) {
+	if int(id) >= len(g.OSM.Ways) || g.OSM.Ways[id].ID == 0 {
+		return WayRef{}, false
+	}
+	return g.OSM.Ways[id], true
+}
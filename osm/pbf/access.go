@@ -0,0 +1,173 @@
+package osm
+
+import (
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/qedus/osmpbf"
+)
+
+// accessKeys are the access tags that apply to each mode, from the most
+// general to the most specific one. A specific tag overrides a general one,
+// so motor_vehicle=yes allows driving on a way tagged access=no.
+var accessKeys = map[Mode][]string{
+	Driving: {"access", "vehicle", "motor_vehicle", "motorcar"},
+	Cycling: {"access", "vehicle", "bicycle"},
+}
+
+// deniedAccess are the access values that forbid the passage.
+var deniedAccess = map[string]struct{}{
+	"no": {}, "private": {}, "agricultural": {}, "forestry": {},
+	"emergency": {}, "military": {}, "use_sidepath": {}, "discouraged": {},
+}
+
+// destinationAccess are the access values that let the mode pass only to reach
+// a place on the way, the edges of those ways pay the destination penalty.
+var destinationAccess = map[string]struct{}{
+	"destination": {}, "customers": {}, "delivery": {},
+}
+
+// impliedAccess are the highway classes a mode can not use unless an access
+// tag says otherwise. Ferries carry cars only when tagged so, like
+// motor_vehicle=yes.
+var impliedAccess = map[Mode]map[string]string{
+	Driving: {"ferry": "no"},
+	Cycling: {"motorway": "no", "motorway_link": "no"},
+}
+
+// blockingBarriers are the barriers a mode can not pass unless an access tag
+// of the barrier node says otherwise. Any other barrier lets the mode pass
+// with a penalty.
+var blockingBarriers = map[Mode]map[string]struct{}{
+	Driving: {
+		"bollard": {}, "block": {}, "jersey_barrier": {}, "cycle_barrier": {},
+		"kissing_gate": {}, "stile": {}, "turnstile": {}, "full-height_turnstile": {},
+		"log": {}, "fence": {}, "wall": {}, "chain": {}, "bus_trap": {},
+	},
+	Cycling: {
+		"stile": {}, "turnstile": {}, "full-height_turnstile": {},
+		"fence": {}, "wall": {}, "kissing_gate": {},
+	},
+}
+
+// freeBarriers are the barriers a mode passes without penalty.
+var freeBarriers = map[Mode]map[string]struct{}{
+	Driving: {},
+	Cycling: {"bollard": {}, "block": {}},
+}
+
+// accessValue resolves the access hierarchy of a mode on the given tags. Suffix
+// is appended to the keys, like ":forward", to resolve a directional access.
+// It returns the value of the most specific tag found, if any.
+func accessValue(tags map[string]string, mode Mode, suffix string) (value string, ok bool) {
+	for _, key := range accessKeys[mode] {
+		if v, found := tags[key+suffix]; found {
+			value, ok = v, true
+		}
+	}
+	return value, ok
+}
+
+// access returns whether the mode is allowed by the access tags and if any
+// tag decided it, see accessValue.
+func access(tags map[string]string, mode Mode, suffix string) (allowed, ok bool) {
+	v, ok := accessValue(tags, mode, suffix)
+	if !ok {
+		return true, false
+	}
+	_, denied := deniedAccess[v]
+	return !denied, true
+}
+
+// destinationOnly reports whether the mode can use the way only to reach a
+// place on it, as with access=destination.
+func destinationOnly(tags map[string]string, mode Mode) bool {
+	v, _ := accessValue(tags, mode, "")
+	_, ok := destinationAccess[v]
+	return ok
+}
+
+// wayAccess reports whether the mode is allowed on the way in any direction.
+func wayAccess(tags map[string]string, mode Mode) bool {
+	if allowed, ok := access(tags, mode, ""); ok {
+		return allowed
+	}
+	kind := tags["highway"]
+	if tags["route"] == "ferry" {
+		kind = "ferry"
+	}
+	if v, ok := impliedAccess[mode][kind]; ok {
+		_, denied := deniedAccess[v]
+		return !denied
+	}
+	return true
+}
+
+// oneway resolves the oneway tags of the way for the mode. It returns
+// whether the way can be travelled forward and backward.
+func oneway(tags map[string]string, mode Mode) (forward, backward bool) {
+	value, ok := tags["oneway"]
+	if mode == Cycling {
+		switch tags["cycleway"] {
+		case "opposite", "opposite_track", "opposite_lane":
+			return true, true
+		}
+		if v, found := tags["oneway:bicycle"]; found {
+			value, ok = v, true
+		}
+	}
+	if !ok {
+		// roundabouts and motorways are oneway unless tagged otherwise.
+		switch {
+		case tags["junction"] == "roundabout", tags["junction"] == "circular":
+			value = "yes"
+		case tags["highway"] == "motorway":
+			value = "yes"
+		}
+	}
+	switch value {
+	case "yes", "true", "1":
+		return true, false
+	case "-1", "reverse":
+		return false, true
+	}
+	return true, true
+}
+
+// barrierPassage tells if the mode can pass through a node, and if so,
+// whether it has to pay the barrier penalty.
+func barrierPassage(tags map[string]string, mode Mode) (pass, penalty bool) {
+	barrier, ok := tags["barrier"]
+	if !ok || barrier == "no" {
+		return true, false
+	}
+	if allowed, ok := access(tags, mode, ""); ok {
+		return allowed, allowed
+	}
+	if _, blocks := blockingBarriers[mode][barrier]; blocks {
+		return false, false
+	}
+	_, free := freeBarriers[mode][barrier]
+	return true, !free
+}
+
+// edgeDirectionFromWay returns the direction the mode travels the way in, or
+// -1 when the directional tags forbid both.
+func edgeDirectionFromWay(w osmpbf.Way, mode Mode) graph.EdgeDirection {
+	tags := w.Tags
+	forward, backward := oneway(tags, mode)
+	// directional access tags, like motor_vehicle:backward=no.
+	if allowed, ok := access(tags, mode, ":forward"); ok {
+		forward = allowed
+	}
+	if allowed, ok := access(tags, mode, ":backward"); ok {
+		backward = allowed
+	}
+	switch {
+	case forward && backward:
+		return graph.Bidirectional
+	case forward:
+		return graph.LeftToRight
+	case backward:
+		return graph.RightToLeft
+	}
+	return -1
+}
@@ -0,0 +1,160 @@
+package osm
+
+import (
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/golang/geo/s2"
+	"github.com/qedus/osmpbf"
+	"testing"
+)
+
+func TestAccess(t *testing.T) {
+	tests := []struct {
+		name        string
+		tags        map[string]string
+		mode        Mode
+		allowed     bool
+		destination bool
+	}{
+		{"untagged", map[string]string{"highway": "primary"}, Driving, true, false},
+		{"denied", map[string]string{"highway": "primary", "access": "no"}, Driving, false, false},
+		{"private", map[string]string{"highway": "service", "access": "private"}, Cycling, false, false},
+		{"specific over general", map[string]string{"highway": "primary", "access": "no", "motor_vehicle": "yes"}, Driving, true, false},
+		{"general over nothing", map[string]string{"highway": "primary", "access": "yes", "motorcar": "no"}, Driving, false, false},
+		{"other mode", map[string]string{"highway": "primary", "bicycle": "no"}, Driving, true, false},
+		{"vehicle", map[string]string{"highway": "primary", "vehicle": "no"}, Cycling, false, false},
+		{"bicycle over vehicle", map[string]string{"highway": "primary", "vehicle": "no", "bicycle": "designated"}, Cycling, true, false},
+		{"destination", map[string]string{"highway": "residential", "access": "destination"}, Driving, true, true},
+		{"customers", map[string]string{"highway": "service", "motor_vehicle": "customers"}, Driving, true, true},
+		{"destination overridden", map[string]string{"highway": "residential", "access": "destination", "bicycle": "yes"}, Cycling, true, false},
+		{"implied motorway", map[string]string{"highway": "motorway"}, Cycling, false, false},
+		{"implied motorway allowed", map[string]string{"highway": "motorway", "bicycle": "yes"}, Cycling, true, false},
+		{"ferry", map[string]string{"route": "ferry"}, Driving, false, false},
+		{"ferry with cars", map[string]string{"route": "ferry", "motor_vehicle": "yes"}, Driving, true, false},
+		{"ferry by bike", map[string]string{"route": "ferry"}, Cycling, true, false},
+	}
+	for _, tt := range tests {
+		if allowed := wayAccess(tt.tags, tt.mode); allowed != tt.allowed {
+			t.Errorf("%s: expected allowed %v, got %v", tt.name, tt.allowed, allowed)
+		}
+		if destination := destinationOnly(tt.tags, tt.mode); destination != tt.destination {
+			t.Errorf("%s: expected destination %v, got %v", tt.name, tt.destination, destination)
+		}
+	}
+}
+
+func TestEdgeDirectionFromWay(t *testing.T) {
+	tests := []struct {
+		name string
+		tags map[string]string
+		mode Mode
+		dir  graph.EdgeDirection
+	}{
+		{"two way", map[string]string{"highway": "primary"}, Driving, graph.Bidirectional},
+		{"oneway", map[string]string{"highway": "primary", "oneway": "yes"}, Driving, graph.LeftToRight},
+		{"oneway true", map[string]string{"highway": "primary", "oneway": "true"}, Driving, graph.LeftToRight},
+		{"oneway no", map[string]string{"highway": "primary", "oneway": "no"}, Driving, graph.Bidirectional},
+		{"reversed", map[string]string{"highway": "primary", "oneway": "-1"}, Driving, graph.RightToLeft},
+		{"reversed by name", map[string]string{"highway": "primary", "oneway": "reverse"}, Driving, graph.RightToLeft},
+		{"roundabout", map[string]string{"highway": "primary", "junction": "roundabout"}, Driving, graph.LeftToRight},
+		{"roundabout tagged two way", map[string]string{"highway": "primary", "junction": "roundabout", "oneway": "no"}, Driving, graph.Bidirectional},
+		{"motorway", map[string]string{"highway": "motorway"}, Driving, graph.LeftToRight},
+		{"contraflow", map[string]string{"highway": "residential", "oneway": "yes", "cycleway": "opposite_lane"}, Cycling, graph.Bidirectional},
+		{"contraflow for cars", map[string]string{"highway": "residential", "oneway": "yes", "cycleway": "opposite_lane"}, Driving, graph.LeftToRight},
+		{"oneway bicycle no", map[string]string{"highway": "residential", "oneway": "yes", "oneway:bicycle": "no"}, Cycling, graph.Bidirectional},
+		{"backward denied", map[string]string{"highway": "primary", "motor_vehicle:backward": "no"}, Driving, graph.LeftToRight},
+		{"forward denied", map[string]string{"highway": "primary", "vehicle:forward": "no"}, Driving, graph.RightToLeft},
+		{"both denied", map[string]string{"highway": "primary", "oneway": "yes", "motor_vehicle:forward": "no"}, Driving, -1},
+	}
+	for _, tt := range tests {
+		if dir := edgeDirectionFromWay(osmpbf.Way{Tags: tt.tags}, tt.mode); dir != tt.dir {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.dir, dir)
+		}
+	}
+}
+
+func TestBarrierPassage(t *testing.T) {
+	tests := []struct {
+		name          string
+		tags          map[string]string
+		mode          Mode
+		pass, penalty bool
+	}{
+		{"no barrier", nil, Driving, true, false},
+		{"barrier no", map[string]string{"barrier": "no"}, Driving, true, false},
+		{"gate", map[string]string{"barrier": "gate"}, Driving, true, true},
+		{"bollard", map[string]string{"barrier": "bollard"}, Driving, false, false},
+		{"bollard by bike", map[string]string{"barrier": "bollard"}, Cycling, true, false},
+		{"stile by bike", map[string]string{"barrier": "stile"}, Cycling, false, false},
+		{"private gate", map[string]string{"barrier": "gate", "access": "private"}, Driving, false, false},
+		{"bollard opened", map[string]string{"barrier": "bollard", "motor_vehicle": "yes"}, Driving, true, true},
+		{"gate for bikes", map[string]string{"barrier": "gate", "motor_vehicle": "no", "bicycle": "yes"}, Cycling, true, true},
+		{"gate closed to cars", map[string]string{"barrier": "gate", "motor_vehicle": "no", "bicycle": "yes"}, Driving, false, false},
+	}
+	for _, tt := range tests {
+		if pass, penalty := barrierPassage(tt.tags, tt.mode); pass != tt.pass || penalty != tt.penalty {
+			t.Errorf("%s: expected %v, %v, got %v, %v", tt.name, tt.pass, tt.penalty, pass, penalty)
+		}
+	}
+}
+
+// buildGraph builds a graph from OSM nodes and ways as the file readers do.
+func buildGraph(filter Filter, nodes []*osmpbf.Node, ways []*osmpbf.Way) graph.Graph {
+	g := graph.Graph{}
+	if filter.Attributes {
+		g.Attributes = graph.NewAttributes()
+	}
+	b := newBuilder(filter, &g, make(map[int64]int32))
+	for _, n := range nodes {
+		b.addNode(n)
+	}
+	for _, w := range ways {
+		b.addWay(w)
+	}
+	return g
+}
+
+func TestBuilder_Penalties(t *testing.T) {
+	nodes := []*osmpbf.Node{
+		{ID: 1, Lat: 4.6, Lon: -74.08},
+		{ID: 2, Lat: 4.6, Lon: -74.079, Tags: map[string]string{"barrier": "gate"}},
+		{ID: 3, Lat: 4.6, Lon: -74.078},
+		{ID: 4, Lat: 4.601, Lon: -74.078, Tags: map[string]string{"barrier": "gate"}},
+		{ID: 5, Lat: 4.602, Lon: -74.078},
+	}
+	ways := []*osmpbf.Way{
+		// through a gate, and to a gate at a dead end.
+		{ID: 10, NodeIDs: []int64{1, 2, 3}, Tags: map[string]string{"highway": "residential"}},
+		{ID: 11, NodeIDs: []int64{3, 4}, Tags: map[string]string{"highway": "residential"}},
+		{ID: 12, NodeIDs: []int64{3, 5}, Tags: map[string]string{"highway": "residential", "access": "destination"}},
+		// a way no direction can be travelled.
+		{ID: 13, NodeIDs: []int64{1, 5}, Tags: map[string]string{"highway": "residential", "oneway": "yes", "motor_vehicle:forward": "no"}},
+	}
+	filter := Filter{Mode: Driving, BarrierPenalty: 100, DestinationPenalty: 1000}
+	g := buildGraph(filter, nodes, ways)
+	distance := func(a, b int32) float32 {
+		return graph.Distance(s2.CellID(g.Nodes[a].Location), s2.CellID(g.Nodes[b].Location))
+	}
+
+	path := g.ShortestPath(graph.ShortestPathCriteria{From: 0, To: 2})
+	if expected := distance(0, 1) + distance(1, 2) + 100; !near(path.Cost, expected) {
+		t.Fatalf("expected the gate paid once, got %v instead of %v", path.Cost, expected)
+	}
+	path = g.ShortestPath(graph.ShortestPathCriteria{From: 2, To: 3})
+	if expected := distance(2, 3) + 100; !near(path.Cost, expected) {
+		t.Fatalf("expected the whole penalty at the dead end, got %v instead of %v", path.Cost, expected)
+	}
+	if e, _ := g.EdgeBetween(3, 2); !near(e.Weight, distance(2, 3)) {
+		t.Fatalf("expected no penalty leaving the gate, got %v", e.Weight)
+	}
+	path = g.ShortestPath(graph.ShortestPathCriteria{From: 2, To: 4})
+	if expected := distance(2, 4) + 1000; !near(path.Cost, expected) {
+		t.Fatalf("expected the destination penalty, got %v instead of %v", path.Cost, expected)
+	}
+	if len(g.Outgoing(0)) != 1 || len(g.Incoming(0)) != 1 || g.Edges() != 16 {
+		t.Fatalf("expected the way without direction skipped, got %d edges", g.Edges())
+	}
+}
+
+func near(a, b float32) bool {
+	return a-b < 1e-3 && b-a < 1e-3
+}
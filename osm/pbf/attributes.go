@@ -0,0 +1,33 @@
+package osm
+
+import (
+	graph "github.com/JesseleDuran/gograph"
+	"math"
+	"strconv"
+)
+
+// wayAttributes extracts the edge attributes from the tags of a way.
+func wayAttributes(tags map[string]string) graph.EdgeAttributes {
+	attr := graph.EdgeAttributes{
+		Name:    tags["name"],
+		Ref:     tags["ref"],
+		Highway: tags["highway"],
+		Surface: tags["surface"],
+	}
+	if lanes, err := strconv.Atoi(tags["lanes"]); err == nil && lanes > 0 && lanes <= math.MaxUint8 {
+		attr.Lanes = uint8(lanes)
+	}
+	if kmh, ok := ParseMaxSpeed(tags["maxspeed"]); ok && kmh <= math.MaxUint16 {
+		attr.MaxSpeed = uint16(math.Round(kmh))
+	}
+	if tags["toll"] == "yes" {
+		attr.Flags |= graph.Toll
+	}
+	if v, ok := tags["bridge"]; ok && v != "no" {
+		attr.Flags |= graph.Bridge
+	}
+	if v, ok := tags["tunnel"]; ok && v != "no" {
+		attr.Flags |= graph.Tunnel
+	}
+	return attr
+}
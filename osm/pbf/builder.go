@@ -0,0 +1,208 @@
+package osm
+
+import (
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/golang/geo/s2"
+	"github.com/qedus/osmpbf"
+)
+
+// builder adds OSM nodes and ways to a graph.
+type builder struct {
+	filter Filter
+	g      *graph.Graph
+	// nodes maps the OSM node IDs to graph node IDs.
+	nodes map[int64]int32
+	// nodeTags are the tags of the graph nodes, only kept for the nodes that have any.
+	nodeTags map[int32]map[string]string
+	// pending are the nodes added to the graph once a way uses them.
+	pending map[int64]*osmpbf.Node
+	// index keeps the edge index of the graph updated as edges are added,
+	// rebuild is set when that was not possible. added are the edges related
+	// since the index is kept.
+	index   bool
+	rebuild bool
+	added   []graph.EdgeID
+}
+
+func newBuilder(filter Filter, g *graph.Graph, nodes map[int64]int32) *builder {
+	return &builder{
+		filter:   filter,
+		g:        g,
+		nodes:    nodes,
+		nodeTags: make(map[int32]map[string]string),
+		pending:  make(map[int64]*osmpbf.Node),
+	}
+}
+
+// addNode adds an OSM node to the graph.
+func (b *builder) addNode(n *osmpbf.Node) int32 {
+	id := b.g.AddNode(graph.Node{
+		Location: CoordinatesToCellID(n.Lat, n.Lon),
+	})
+	b.nodes[n.ID] = id
+	b.g.SetOSMNode(id, n.ID)
+	if len(n.Tags) > 0 {
+		b.nodeTags[id] = n.Tags
+	}
+	return id
+}
+
+// node returns the graph node ID of an OSM node, adding it to the graph if it
+// is pending and in the coverage.
+func (b *builder) node(osmID int64) (int32, bool) {
+	if id, ok := b.nodes[osmID]; ok {
+		return id, true
+	}
+	n, ok := b.pending[osmID]
+	if !ok || !inCoverage(b.filter.Coverage, n.Lat, n.Lon) {
+		return 0, false
+	}
+	delete(b.pending, osmID)
+	return b.addNode(n), true
+}
+
+// addWay relates the graph nodes of each segment of the way and returns the
+// number of segments related. Ways that are not valid for the mode are ignored.
+func (b *builder) addWay(w *osmpbf.Way) int {
+	if !validWay(*w, b.filter.Mode) {
+		return 0
+	}
+	var attr graph.EdgeAttributes
+	if b.g.Attributes != nil {
+		attr = wayAttributes(w.Tags)
+	}
+	dir := edgeDirectionFromWay(*w, b.filter.Mode)
+	if dir == -1 {
+		return 0
+	}
+	class := wayClass(w.Tags)
+	extra := float32(0)
+	if destinationOnly(w.Tags, b.filter.Mode) {
+		extra = b.filter.DestinationPenalty
+	}
+	// the duration of the way is split by the length of its segments.
+	duration, hasDuration := ParseDuration(w.Tags["duration"])
+	length := float32(0)
+	if hasDuration {
+		length = b.length(w)
+	}
+	related := 0
+	for i := 0; i < len(w.NodeIDs)-1; i++ {
+		idA, ok1 := b.node(w.NodeIDs[i])
+		if !ok1 {
+			continue
+		}
+		idB, ok2 := b.node(w.NodeIDs[i+1])
+		if !ok2 {
+			continue
+		}
+		passA, penaltyA := barrierPassage(b.nodeTags[idA], b.filter.Mode)
+		passB, penaltyB := barrierPassage(b.nodeTags[idB], b.filter.Mode)
+		if !passA || !passB {
+			continue
+		}
+		segmentDuration := float32(0)
+		if length > 0 {
+			segmentDuration = float32(duration) * b.distance(idA, idB) / length
+		}
+		weight := b.weight(w, idA, idB, segmentDuration) + extra
+		// the barrier penalty is paid by the edges entering the barrier, so a
+		// path through it or ending at it pays it once.
+		enterA, enterB := float32(0), float32(0)
+		if penaltyA {
+			enterA = b.filter.BarrierPenalty
+		}
+		if penaltyB {
+			enterB = b.filter.BarrierPenalty
+		}
+		// the segment is new to the edge index if the nodes were not related yet.
+		indexed := graph.EdgeDirection(-1)
+		if b.index {
+			indexed, _ = b.g.EdgeDirectionByNodes(idA, idB)
+		}
+		ref := graph.WayRef{ID: w.ID, Position: int32(i)}
+		forEachEdge(idA, idB, dir, func(from, to int32, reverse bool) {
+			enter := enterB
+			if reverse {
+				enter = enterA
+			}
+			id := b.g.AddEdge(from, to, weight+enter, class)
+			ref.Reverse = reverse
+			b.g.SetEdgeWay(id, ref)
+			if b.g.Attributes != nil {
+				b.g.Attributes.Set(id, attr)
+			}
+			if b.index {
+				b.added = append(b.added, id)
+			}
+		})
+		if b.index && indexed == -1 && !b.g.IndexEdge(idA, idB) {
+			b.rebuild = true
+		}
+		related++
+	}
+	return related
+}
+
+// length returns the length in meters of the part of the way in the graph.
+func (b *builder) length(w *osmpbf.Way) float32 {
+	result := float32(0)
+	for i := 0; i < len(w.NodeIDs)-1; i++ {
+		idA, ok1 := b.node(w.NodeIDs[i])
+		idB, ok2 := b.node(w.NodeIDs[i+1])
+		if ok1 && ok2 {
+			result += b.distance(idA, idB)
+		}
+	}
+	return result
+}
+
+// distance returns the distance in meters between two graph nodes.
+func (b *builder) distance(idA, idB int32) float32 {
+	return graph.Distance(s2.CellID(b.g.Nodes[idA].Location), s2.CellID(b.g.Nodes[idB].Location))
+}
+
+// weight computes the weight of the edge between two nodes of a way.
+func (b *builder) weight(w *osmpbf.Way, idA, idB int32, duration float32) float32 {
+	nodeA, nodeB := b.g.Nodes[idA], b.g.Nodes[idB]
+	if b.filter.SetWeight == nil {
+		return b.distance(idA, idB)
+	}
+	return b.filter.SetWeight(Segment{
+		From: graph.Coordinate{
+			Lat: s2.CellID(nodeA.Location).LatLng().Lat.Degrees(),
+			Lng: s2.CellID(nodeA.Location).LatLng().Lng.Degrees(),
+		},
+		To: graph.Coordinate{
+			Lat: s2.CellID(nodeB.Location).LatLng().Lat.Degrees(),
+			Lng: s2.CellID(nodeB.Location).LatLng().Lng.Degrees(),
+		},
+		WayTags:  w.Tags,
+		FromTags: b.nodeTags[idA],
+		ToTags:   b.nodeTags[idB],
+		Duration: duration,
+	})
+}
+
+// forEachEdge calls fn with each directed edge that RelateNodes would create
+// for the given nodes and direction. reverse is set for the edges from b to a.
+func forEachEdge(a, b int32, dir graph.EdgeDirection, fn func(from, to int32, reverse bool)) {
+	switch dir {
+	case graph.Bidirectional:
+		fn(a, b, false)
+		fn(b, a, true)
+	case graph.LeftToRight:
+		fn(a, b, false)
+	case graph.RightToLeft:
+		fn(b, a, true)
+	}
+}
+
+// inCoverage reports whether the coordinates are inside the coverage loop.
+// An empty loop covers everything.
+func inCoverage(loop s2.Loop, lat, lng float64) bool {
+	if loop.NumVertices() == 0 {
+		return true
+	}
+	return loop.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng)))
+}
@@ -0,0 +1,344 @@
+package osm
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/golang/geo/s2"
+	osmapi "github.com/paulmach/osm"
+	"github.com/qedus/osmpbf"
+	"io"
+	"math"
+)
+
+// ErrNoOSMRefs is returned when a change is applied to a graph built without
+// the OSM node and way references.
+var ErrNoOSMRefs = errors.New("osm: the graph has no OSM references")
+
+// MissingNodesError is returned by ApplyChange when ways of the change use
+// nodes that are neither in the graph nor in the change, so their segments
+// with those nodes are not added. It happens when the graph is older than the
+// change, or for the ways that leave the coverage of the graph. The rest of
+// the change is applied.
+type MissingNodesError struct {
+	// Ways are the OSM IDs of the missing nodes of each way.
+	Ways map[int64][]int64
+}
+
+func (e *MissingNodesError) Error() string {
+	// the way with the lowest ID is named, so the message is stable.
+	way := int64(math.MaxInt64)
+	for id := range e.Ways {
+		if id < way {
+			way = id
+		}
+	}
+	if len(e.Ways) == 1 {
+		return fmt.Sprintf("osm: way %d uses the nodes %v, missing from the graph and the change", way, e.Ways[way])
+	}
+	return fmt.Sprintf("osm: %d ways use nodes missing from the graph and the change, like way %d with %v", len(e.Ways), way, e.Ways[way])
+}
+
+// ChangeReport summarizes what a change file did to a graph. The edges are
+// counted once per direction.
+type ChangeReport struct {
+	NodesCreated, NodesModified, NodesDeleted int
+	WaysCreated, WaysModified, WaysDeleted    int
+	EdgesAdded, EdgesRemoved, EdgesUpdated    int
+	// IndexRebuilt is set when the edge index could not be updated in place.
+	IndexRebuilt bool
+}
+
+// ApplyChange applies an OSM change file (.osc) to a graph built from OSM data
+// with the same filter. Nodes and ways are created, modified and deleted in
+// the order of the file and the edge index is updated in place.
+// The tags of the nodes already in the graph are not kept, so the barriers
+// they could hold are only seen on the nodes of the change. A frozen graph is
+// thawed to apply the change and frozen again.
+// A *MissingNodesError is returned, with the report of the whole change, when
+// ways use nodes that are not in the graph.
+func ApplyChange(g *graph.Graph, r io.Reader, filter Filter) (ChangeReport, error) {
+	report := ChangeReport{}
+	if len(g.OSM.Nodes) == 0 && len(g.Nodes) > 0 {
+		return report, ErrNoOSMRefs
+	}
+	if g.OSM.NodeIDs == nil {
+		g.OSM.NodeIDs = make(map[int64]int32)
+	}
+	if g.IsFrozen() {
+		g.Thaw()
+		defer g.Freeze()
+	}
+	a := &applier{
+		builder:  newBuilder(filter, g, g.OSM.NodeIDs),
+		report:   &report,
+		wayEdges: make(map[int64][]graph.EdgeID),
+		missing:  make(map[int64][]int64),
+	}
+	a.index = true
+	for id, ref := range g.OSM.Ways {
+		if ref.ID != 0 {
+			a.wayEdges[ref.ID] = append(a.wayEdges[ref.ID], graph.EdgeID(id))
+		}
+	}
+
+	d := xml.NewDecoder(r)
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("osm: reading change: %w", err)
+		}
+		start, ok := t.(xml.StartElement)
+		if !ok || start.Name.Local == "osmChange" {
+			continue
+		}
+		action := start.Name.Local
+		if action != "create" && action != "modify" && action != "delete" {
+			if err := d.Skip(); err != nil {
+				return report, fmt.Errorf("osm: reading change: %w", err)
+			}
+			continue
+		}
+		var block osmapi.OSM
+		if err := d.DecodeElement(&block, &start); err != nil {
+			return report, fmt.Errorf("osm: reading %s block: %w", action, err)
+		}
+		if err := a.apply(action, block); err != nil {
+			return report, err
+		}
+	}
+	if a.rebuild {
+		g.EdgeIndex = g.BuildEdgeIndex()
+		report.IndexRebuilt = true
+	}
+	if len(a.missing) > 0 {
+		return report, &MissingNodesError{Ways: a.missing}
+	}
+	return report, nil
+}
+
+// applier applies the blocks of a change file.
+type applier struct {
+	*builder
+	report *ChangeReport
+	// wayEdges are the edges of each way in the graph.
+	wayEdges map[int64][]graph.EdgeID
+	// missing are the nodes of each way that are not in the graph.
+	missing map[int64][]int64
+}
+
+func (a *applier) apply(action string, block osmapi.OSM) error {
+	for _, n := range block.Nodes {
+		node := &osmpbf.Node{ID: int64(n.ID), Lat: n.Lat, Lon: n.Lon, Tags: n.Tags.Map()}
+		switch action {
+		case "create":
+			a.pending[node.ID] = node
+			a.report.NodesCreated++
+		case "modify":
+			if err := a.modifyNode(node); err != nil {
+				return err
+			}
+			a.report.NodesModified++
+		case "delete":
+			a.deleteNode(node.ID)
+			a.report.NodesDeleted++
+		}
+	}
+	for _, w := range block.Ways {
+		ids := make([]int64, len(w.Nodes))
+		for i, n := range w.Nodes {
+			ids[i] = int64(n.ID)
+		}
+		way := &osmpbf.Way{ID: int64(w.ID), Tags: w.Tags.Map(), NodeIDs: ids}
+		switch action {
+		case "create":
+			a.addWay(way)
+			a.report.WaysCreated++
+		case "modify":
+			a.removeWay(way.ID)
+			a.addWay(way)
+			a.report.WaysModified++
+		case "delete":
+			a.removeWay(way.ID)
+			a.report.WaysDeleted++
+		}
+	}
+	return nil
+}
+
+// addWay adds a way to the graph, keeping track of its edges.
+func (a *applier) addWay(w *osmpbf.Way) {
+	if validWay(*w, a.filter.Mode) {
+		for _, osmID := range w.NodeIDs {
+			_, inGraph := a.nodes[osmID]
+			_, inChange := a.pending[osmID]
+			if !inGraph && !inChange {
+				a.missing[w.ID] = append(a.missing[w.ID], osmID)
+			}
+		}
+	}
+	a.added = a.added[:0]
+	a.builder.addWay(w)
+	a.report.EdgesAdded += len(a.added)
+	a.wayEdges[w.ID] = append(a.wayEdges[w.ID], a.added...)
+}
+
+// removeWay removes the edges of a way from the graph.
+func (a *applier) removeWay(id int64) {
+	for _, edgeID := range a.wayEdges[id] {
+		ends, ok := a.g.EdgeByID(edgeID)
+		if !ok || !a.g.RemoveEdgeByID(edgeID) {
+			continue
+		}
+		a.report.EdgesRemoved++
+		if dir, _ := a.g.EdgeDirectionByNodes(ends.From, ends.To); dir == -1 {
+			a.g.UnindexEdge(ends.From, ends.To)
+		}
+	}
+	delete(a.wayEdges, id)
+}
+
+// modifyNode moves a node of the graph and updates the weights of its edges
+// to the new distances. Nodes that are not in the graph become pending, as a
+// way of the change can use them.
+// The penalties in the weights are kept, and the barrier penalty follows the
+// new tags of the node when the old ones are known. With a custom SetWeight
+// the rest of the weight is rescaled to the new length, the way tags not being
+// kept, so the destination penalty and the barrier penalty of the nodes out of
+// the change are rescaled with it. The new weights are computed first, so the
+// node and its edges are left as they were when one can not be rescaled.
+func (a *applier) modifyNode(n *osmpbf.Node) error {
+	id, ok := a.nodes[n.ID]
+	if !ok {
+		a.pending[n.ID] = n
+		return nil
+	}
+	oldTags, known := a.nodeTags[id]
+	setTags := func() {
+		if len(n.Tags) > 0 {
+			a.nodeTags[id] = n.Tags
+		} else {
+			delete(a.nodeTags, id)
+		}
+	}
+	if pass, _ := barrierPassage(n.Tags, a.filter.Mode); !pass {
+		setTags()
+		a.unlinkNode(id)
+		return nil
+	}
+	enter := a.barrierPenalty(n.Tags)
+	enterBefore := enter
+	if known {
+		enterBefore = a.barrierPenalty(oldTags)
+	}
+	location := CoordinatesToCellID(n.Lat, n.Lon)
+	old := a.g.Nodes[id].Location
+	if location == old && enter == enterBefore {
+		setTags()
+		return nil
+	}
+	type update struct {
+		edge     graph.EdgeID
+		other    int32
+		weight   float32
+		entering bool
+	}
+	updates := make([]update, 0)
+	neighbors := make(map[int32]bool)
+	for _, e := range a.g.Outgoing(id) {
+		updates = append(updates, update{edge: e.EdgeID, other: e.ID, weight: e.Weight})
+		neighbors[e.ID] = true
+	}
+	for _, e := range a.g.Incoming(id) {
+		updates = append(updates, update{edge: e.EdgeID, other: e.ID, weight: e.Weight, entering: true})
+		neighbors[e.ID] = true
+	}
+	for i, u := range updates {
+		at := s2.CellID(a.g.Nodes[u.other].Location)
+		before := graph.Distance(s2.CellID(old), at)
+		after := graph.Distance(s2.CellID(location), at)
+		// the barrier penalty is in the edges entering the barrier.
+		penalty, penaltyBefore := float32(0), float32(0)
+		if u.entering {
+			penalty, penaltyBefore = enter, enterBefore
+		} else if tags, ok := a.nodeTags[u.other]; ok {
+			penalty = a.barrierPenalty(tags)
+			penaltyBefore = penalty
+		}
+		rest := u.weight - penaltyBefore
+		switch {
+		case a.filter.SetWeight == nil:
+			rest += after - before
+		case before == after:
+		case before == 0:
+			return fmt.Errorf("osm: can not rescale the weight of the edge %d moved by node %d", u.edge, n.ID)
+		default:
+			rest *= after / before
+		}
+		updates[i].weight = rest + penalty
+	}
+
+	setTags()
+	if location != old {
+		for neighbor := range neighbors {
+			a.g.UnindexEdge(id, neighbor)
+		}
+		node := a.g.Nodes[id]
+		node.Location = location
+		a.g.Nodes[id] = node
+	}
+	for _, u := range updates {
+		a.g.SetEdgeWeightByID(u.edge, u.weight)
+		a.report.EdgesUpdated++
+	}
+	if location != old {
+		for neighbor := range neighbors {
+			if !a.g.IndexEdge(id, neighbor) {
+				a.rebuild = true
+			}
+		}
+	}
+	return nil
+}
+
+// barrierPenalty returns the penalty of entering a node with the given tags.
+func (a *applier) barrierPenalty(tags map[string]string) float32 {
+	if _, penalty := barrierPassage(tags, a.filter.Mode); penalty {
+		return a.filter.BarrierPenalty
+	}
+	return 0
+}
+
+// deleteNode removes a node of the graph with the edges left on it.
+func (a *applier) deleteNode(osmID int64) {
+	delete(a.pending, osmID)
+	id, ok := a.nodes[osmID]
+	if !ok {
+		return
+	}
+	a.unlinkNode(id)
+	delete(a.nodes, osmID)
+	a.g.OSM.Nodes[id] = 0
+}
+
+// unlinkNode removes all the edges of a node.
+func (a *applier) unlinkNode(id int32) {
+	out := append([]graph.Edge{}, a.g.Outgoing(id)...)
+	in := append([]graph.Edge{}, a.g.Incoming(id)...)
+	for _, e := range out {
+		if a.g.RemoveEdgeByID(e.EdgeID) {
+			a.report.EdgesRemoved++
+		}
+		a.g.UnindexEdge(id, e.ID)
+	}
+	for _, e := range in {
+		if a.g.RemoveEdgeByID(e.EdgeID) {
+			a.report.EdgesRemoved++
+		}
+		a.g.UnindexEdge(e.ID, id)
+	}
+}
@@ -0,0 +1,195 @@
+package osm
+
+import (
+	"errors"
+	"fmt"
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/JesseleDuran/gograph/nearest_edge"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// indexed describes the segments of an edge index by their nodes and
+// coordinates.
+func indexed(index nearest_edge.Node) []string {
+	segments := make([]string, 0)
+	for _, s := range index.Intersecting(index.Quadrant) {
+		a, b := s.A, s.B
+		if a.ID > b.ID {
+			a, b = b, a
+		}
+		segments = append(segments, fmt.Sprintf("%d %.6f %.6f - %d %.6f %.6f", a.ID, a.X, a.Y, b.ID, b.X, b.Y))
+	}
+	sort.Strings(segments)
+	return segments
+}
+
+// applyChange builds the graph of the fixture network and applies a change to it.
+func applyChange(t *testing.T, filter Filter, change string) (graph.Graph, ChangeReport, error) {
+	filter.Path = filepath.Join("testdata", "network.osm")
+	g := MakeGraphFromFile(filter)
+	g.EdgeIndex = g.BuildEdgeIndex()
+	report, err := ApplyChange(&g, strings.NewReader(change), filter)
+	return g, report, err
+}
+
+func TestApplyChange(t *testing.T) {
+	change, err := os.ReadFile(filepath.Join("testdata", "change.osc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, setWeight := range map[string]SetWeight{"distance": nil, "travel time": TravelTime("CO", Driving)} {
+		filter := Filter{Mode: Driving, SetWeight: setWeight, BarrierPenalty: 60, DestinationPenalty: 30, Attributes: true}
+		g, report, err := applyChange(t, filter, string(change))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		// the change applied is the same as importing the data changed.
+		filter.Path = filepath.Join("testdata", "network_changed.osm")
+		expected := MakeGraphFromFile(filter)
+		if edges := osmEdges(g); !reflect.DeepEqual(edges, osmEdges(expected)) {
+			t.Fatalf("%s: unexpected edges\n%v\nexpected\n%v", name, strings.Join(edges, "\n"), strings.Join(osmEdges(expected), "\n"))
+		}
+		if report.NodesCreated != 1 || report.NodesModified != 2 || report.NodesDeleted != 1 ||
+			report.WaysCreated != 2 || report.WaysModified != 1 || report.WaysDeleted != 1 {
+			t.Fatalf("%s: unexpected report %+v", name, report)
+		}
+		if _, ok := g.NodeByOSMID(8); ok {
+			t.Fatalf("%s: expected the node 8 deleted", name)
+		}
+		// the edge index is updated in place, with the moved nodes and without
+		// the edges of the node 8.
+		if report.IndexRebuilt {
+			t.Fatalf("%s: expected the edge index updated in place", name)
+		}
+		if segments := indexed(g.EdgeIndex); !reflect.DeepEqual(segments, indexed(g.BuildEdgeIndex())) {
+			t.Fatalf("%s: the edge index is not up to date: %v", name, segments)
+		}
+	}
+}
+
+func TestApplyChange_MissingNodes(t *testing.T) {
+	// the node 7 is only used by a footway, so it is not in the driving graph.
+	change := `<osmChange version="0.6">
+  <create>
+    <way id="108" version="1">
+      <nd ref="2"/>
+      <nd ref="7"/>
+      <tag k="highway" v="residential"/>
+    </way>
+    <way id="109" version="1">
+      <nd ref="1"/>
+      <nd ref="3"/>
+      <tag k="highway" v="residential"/>
+    </way>
+  </create>
+</osmChange>`
+	g, report, err := applyChange(t, Filter{Mode: Driving}, change)
+	missing := &MissingNodesError{}
+	if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Ways, map[int64][]int64{108: {7}}) {
+		t.Fatalf("expected the node 7 missing, got %v", err)
+	}
+	// the rest of the change is applied.
+	if report.WaysCreated != 2 || report.EdgesAdded != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	from, _ := g.NodeByOSMID(1)
+	to, _ := g.NodeByOSMID(3)
+	if _, ok := g.EdgeBetween(from, to); !ok {
+		t.Fatal("expected the way 109 added")
+	}
+}
+
+func TestApplyChange_Elevation(t *testing.T) {
+	change, err := os.ReadFile(filepath.Join("testdata", "change.osc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := Filter{Path: filepath.Join("testdata", "network.osm"), Mode: Driving}
+	g := MakeGraphFromFile(filter)
+	g.Elevation = make([]float32, len(g.Nodes))
+	if _, err := ApplyChange(&g, strings.NewReader(string(change)), filter); err != nil {
+		t.Fatal(err)
+	}
+	// the node created by the change has no elevation yet.
+	id, ok := g.NodeByOSMID(10)
+	if !ok || len(g.Elevation) != len(g.Nodes) {
+		t.Fatalf("expected an elevation per node, got %d for %d nodes", len(g.Elevation), len(g.Nodes))
+	}
+	if _, ok := g.NodeElevation(id); ok {
+		t.Fatal("expected no elevation for the created node")
+	}
+	from, _ := g.NodeByOSMID(5)
+	if p := g.ShortestPath(graph.ShortestPathCriteria{From: from, To: id}); p.Nodes[len(p.Nodes)-1] != id || len(p.Elevations) != len(p.Nodes) {
+		t.Fatalf("expected a route to the created node, got %v", p.Nodes)
+	}
+}
+
+func TestApplyChange_RescaleError(t *testing.T) {
+	// the node 11 is at the location of the node 6, so the edge between them
+	// has no length to rescale when the node 6 moves.
+	change := `<osmChange version="0.6">
+  <create>
+    <node id="11" version="1" lat="4.602" lon="-74.08"/>
+    <way id="108" version="1">
+      <nd ref="6"/>
+      <nd ref="11"/>
+      <tag k="highway" v="residential"/>
+    </way>
+  </create>
+  <modify>
+    <node id="6" version="2" lat="4.6021" lon="-74.0799"/>
+  </modify>
+</osmChange>`
+	filter := Filter{Mode: Driving, SetWeight: TravelTime("CO", Driving)}
+	g, _, err := applyChange(t, filter, change)
+	if err == nil {
+		t.Fatal("expected an error rescaling an edge without length")
+	}
+
+	// the node and its edges are left as they were.
+	filter.Path = filepath.Join("testdata", "network.osm")
+	before := MakeGraphFromFile(filter)
+	id, _ := g.NodeByOSMID(6)
+	if g.Nodes[id].Location != before.Nodes[id].Location {
+		t.Fatal("expected the node not moved")
+	}
+	for _, e := range before.Outgoing(id) {
+		if found, _ := g.EdgeBetween(id, e.ID); found.Weight != e.Weight {
+			t.Fatalf("expected the weight %f to %d kept, got %f", e.Weight, e.ID, found.Weight)
+		}
+	}
+	if segments := indexed(g.EdgeIndex); !reflect.DeepEqual(segments, indexed(g.BuildEdgeIndex())) {
+		t.Fatalf("expected the segments of the node kept in the index, got\n%s", strings.Join(segments, "\n"))
+	}
+}
+
+func TestDiff_Imports(t *testing.T) {
+	filter := Filter{Mode: Driving, SetWeight: TravelTime("CO", Driving), Attributes: true}
+	filter.Path = filepath.Join("testdata", "network.osm")
+	before := MakeGraphFromFile(filter)
+	filter.Path = filepath.Join("testdata", "network_changed.osm")
+	after := MakeGraphFromFile(filter)
+
+	// the graphs are imported independently, the edges are matched by their
+	// nodes and ways, not by their EdgeIDs.
+	p := graph.Diff(before, after)
+	// the node 8 is deleted, 10 created and 4 and 6 moved. The edges of way
+	// 106 and the reverse one of way 107 are added, the edges of the moved
+	// nodes change their weight and 3 -> 2 changes its way, 100 now being
+	// one way, to 107. 2 -> 1 is removed, the edge 8 -> 6 goes with its node.
+	if len(p.AddedNodes) != 1 || len(p.ChangedNodes) != 2 || len(p.RemovedNodes) != 1 ||
+		len(p.AddedEdges) != 3 || len(p.ChangedEdges) != 7 || len(p.RemovedEdges) != 1 {
+		t.Fatalf("unexpected patch %+v", p)
+	}
+	if err := before.Apply(p); err != nil {
+		t.Fatal(err)
+	}
+	if edges := osmEdges(before); !reflect.DeepEqual(edges, osmEdges(after)) {
+		t.Fatalf("unexpected edges\n%v\nexpected\n%v", strings.Join(edges, "\n"), strings.Join(osmEdges(after), "\n"))
+	}
+}
@@ -0,0 +1,73 @@
+package osm
+
+import (
+	graph "github.com/JesseleDuran/gograph"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// unpavedSurfaces are the surface values of the unpaved roads.
+var unpavedSurfaces = map[string]struct{}{
+	"unpaved": {}, "gravel": {}, "fine_gravel": {}, "pebblestone": {},
+	"dirt": {}, "earth": {}, "ground": {}, "grass": {}, "mud": {},
+	"sand": {}, "compacted": {}, "woodchips": {},
+}
+
+// wayClass returns the classes of the edges of a way.
+// Tracks are unpaved unless their surface says otherwise.
+func wayClass(tags map[string]string) graph.EdgeClass {
+	class := graph.EdgeClass(0)
+	if tags["toll"] == "yes" {
+		class |= graph.ClassToll
+	}
+	if tags["route"] == "ferry" {
+		class |= graph.ClassFerry
+	}
+	surface, ok := tags["surface"]
+	if _, unpaved := unpavedSurfaces[surface]; unpaved || (!ok && tags["highway"] == "track") {
+		class |= graph.ClassUnpaved
+	}
+	switch tags["highway"] {
+	case "motorway", "motorway_link":
+		class |= graph.ClassMotorway
+	}
+	return class
+}
+
+// isoDuration matches the ISO 8601 durations, like PT1H30M.
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseDuration parses an OSM duration value into seconds. It understands
+// minutes ("45"), "HH:MM", "HH:MM:SS" and ISO 8601 durations ("PT1H30M").
+func ParseDuration(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	if m := isoDuration.FindStringSubmatch(value); m != nil && value != "P" && value != "PT" {
+		seconds := 0.0
+		for i, unit := range []float64{86400, 3600, 60, 1} {
+			if m[i+1] != "" {
+				v, _ := strconv.ParseFloat(m[i+1], 64)
+				seconds += v * unit
+			}
+		}
+		return seconds, seconds > 0
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+	seconds := 0.0
+	// a single number is in minutes, otherwise the first part is the hours.
+	units := []float64{60}
+	if len(parts) > 1 {
+		units = []float64{3600, 60, 1}
+	}
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || v < 0 {
+			return 0, false
+		}
+		seconds += v * units[i]
+	}
+	return seconds, seconds > 0
+}
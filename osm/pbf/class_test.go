@@ -0,0 +1,111 @@
+package osm
+
+import (
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/qedus/osmpbf"
+	"testing"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		seconds float64
+		ok      bool
+	}{
+		{"45", 45 * 60, true},
+		{"1.5", 90, true},
+		{" 45 ", 45 * 60, true},
+		{"01:30", 5400, true},
+		{"1:30:15", 5415, true},
+		{"00:00:30", 30, true},
+		{"PT1H30M", 5400, true},
+		{"PT45M", 2700, true},
+		{"PT30S", 30, true},
+		{"PT1.5S", 1.5, true},
+		{"P1D", 86400, true},
+		{"P1DT2H", 93600, true},
+		{"", 0, false},
+		{"0", 0, false},
+		{"00:00", 0, false},
+		{"P", 0, false},
+		{"PT", 0, false},
+		{"PT1H30", 0, false},
+		{"1:2:3:4", 0, false},
+		{"-5", 0, false},
+		{"1:-30", 0, false},
+		{"1h30", 0, false},
+		{"about an hour", 0, false},
+	}
+	for _, tt := range tests {
+		if seconds, ok := ParseDuration(tt.value); seconds != tt.seconds || ok != tt.ok {
+			t.Errorf("ParseDuration(%q) = %v, %v, expected %v, %v", tt.value, seconds, ok, tt.seconds, tt.ok)
+		}
+	}
+}
+
+func TestWayClass(t *testing.T) {
+	tests := []struct {
+		name  string
+		tags  map[string]string
+		class graph.EdgeClass
+	}{
+		{"plain", map[string]string{"highway": "primary"}, 0},
+		{"toll", map[string]string{"highway": "primary", "toll": "yes"}, graph.ClassToll},
+		{"toll no", map[string]string{"highway": "primary", "toll": "no"}, 0},
+		{"ferry", map[string]string{"route": "ferry"}, graph.ClassFerry},
+		{"unpaved", map[string]string{"highway": "residential", "surface": "gravel"}, graph.ClassUnpaved},
+		{"paved", map[string]string{"highway": "residential", "surface": "asphalt"}, 0},
+		{"track", map[string]string{"highway": "track"}, graph.ClassUnpaved},
+		{"paved track", map[string]string{"highway": "track", "surface": "concrete"}, 0},
+		{"motorway", map[string]string{"highway": "motorway"}, graph.ClassMotorway},
+		{"motorway link", map[string]string{"highway": "motorway_link"}, graph.ClassMotorway},
+		{"toll motorway", map[string]string{"highway": "motorway", "toll": "yes"}, graph.ClassToll | graph.ClassMotorway},
+	}
+	for _, tt := range tests {
+		if class := wayClass(tt.tags); class != tt.class {
+			t.Errorf("%s: expected class %b, got %b", tt.name, tt.class, class)
+		}
+	}
+}
+
+func TestBuilder_Ferry(t *testing.T) {
+	nodes := []*osmpbf.Node{
+		{ID: 1, Lat: 4.6, Lon: -74.08},
+		{ID: 2, Lat: 4.6, Lon: -74.07},
+		{ID: 3, Lat: 4.6, Lon: -74.04},
+		{ID: 4, Lat: 4.61, Lon: -74.08},
+		{ID: 5, Lat: 4.61, Lon: -74.04},
+	}
+	ways := []*osmpbf.Way{
+		// a car ferry of half an hour in two segments, a quarter and three
+		// quarters of its length.
+		{ID: 10, NodeIDs: []int64{1, 2, 3}, Tags: map[string]string{"route": "ferry", "duration": "PT30M", "motor_vehicle": "yes"}},
+		// a ferry for people and bikes only, without duration.
+		{ID: 11, NodeIDs: []int64{4, 5}, Tags: map[string]string{"route": "ferry"}},
+		{ID: 12, NodeIDs: []int64{1, 4}, Tags: map[string]string{"highway": "residential"}},
+		{ID: 13, NodeIDs: []int64{3, 5}, Tags: map[string]string{"highway": "residential"}},
+	}
+	g := buildGraph(Filter{Mode: Driving, SetWeight: TravelTime("", Driving)}, nodes, ways)
+	first, _ := g.EdgeBetween(0, 1)
+	second, _ := g.EdgeBetween(1, 2)
+	if !near(first.Weight+second.Weight, 1800) || !near(second.Weight/first.Weight, 3) {
+		t.Fatalf("expected the duration split by length, got %v and %v", first.Weight, second.Weight)
+	}
+	if first.Class != graph.ClassFerry {
+		t.Fatalf("expected a ferry edge, got class %b", first.Class)
+	}
+	if _, ok := g.EdgeBetween(3, 4); ok {
+		t.Fatal("expected the ferry without cars skipped when driving")
+	}
+	if cost := g.Dijkstra(graph.ShortestPathCriteria{From: 0, To: 2}); !near(cost, 1800) {
+		t.Fatalf("expected the ferry taken, got %v", cost)
+	}
+	if cost := g.Dijkstra(graph.ShortestPathCriteria{From: 0, To: 2, Avoid: graph.ClassFerry}); cost != graph.INFINITE {
+		t.Fatalf("expected no path avoiding the ferry, got %v", cost)
+	}
+
+	g = buildGraph(Filter{Mode: Cycling, SetWeight: TravelTime("", Cycling)}, nodes, ways)
+	if e, ok := g.EdgeBetween(3, 4); !ok || e.Class != graph.ClassFerry {
+		t.Fatalf("expected the ferry for bikes, got %v", e)
+	}
+}
@@ -0,0 +1,149 @@
+package osm
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	osmapi "github.com/paulmach/osm"
+	"github.com/qedus/osmpbf"
+	"io"
+	"os"
+	"runtime"
+)
+
+// decoder yields the nodes and ways of an OSM file as *osmpbf.Node and
+// *osmpbf.Way whatever the format of the file, and io.EOF at its end.
+type decoder interface {
+	Decode() (interface{}, error)
+	Close() error
+}
+
+// openDecoder opens an OSM file, PBF or XML, transparently decompressing
+// bzip2 and gzip files. The format is detected from the content of the file.
+func openDecoder(path string) (decoder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	magic, _ := br.Peek(3)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		r = gz
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		r = bzip2.NewReader(br)
+	}
+	br = bufio.NewReader(r)
+	if isXML(br) {
+		return &xmlDecoder{xml: xml.NewDecoder(br), file: f}, nil
+	}
+	d := osmpbf.NewDecoder(br)
+	// use more memory from the start, it is faster
+	d.SetBufferSize(osmpbf.MaxBlobSize)
+	// start decoding with several goroutines, it is faster
+	if err := d.Start(runtime.GOMAXPROCS(-1)); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &pbfDecoder{Decoder: d, file: f}, nil
+}
+
+// isXML reports whether the first non blank byte of the reader opens a tag.
+func isXML(br *bufio.Reader) bool {
+	for i := 1; ; i++ {
+		b, err := br.Peek(i)
+		if err != nil {
+			return false
+		}
+		switch b[i-1] {
+		case ' ', '\t', '\r', '\n', 0xef, 0xbb, 0xbf: // blanks and UTF-8 BOM.
+			continue
+		case '<':
+			return true
+		}
+		return false
+	}
+}
+
+type pbfDecoder struct {
+	*osmpbf.Decoder
+	file *os.File
+}
+
+func (d *pbfDecoder) Close() error {
+	return d.file.Close()
+}
+
+// xmlDecoder reads the nodes and ways of an OSM XML file. The elements that
+// JOSM marks as deleted, with action="delete", are skipped.
+type xmlDecoder struct {
+	xml  *xml.Decoder
+	file *os.File
+}
+
+func (d *xmlDecoder) Decode() (interface{}, error) {
+	for {
+		t, err := d.xml.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := t.(xml.StartElement)
+		if !ok || start.Name.Local == "osm" {
+			continue
+		}
+		if (start.Name.Local != "node" && start.Name.Local != "way") || deleted(start) {
+			if err := d.xml.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if start.Name.Local == "node" {
+			n := osmapi.Node{}
+			if err := d.xml.DecodeElement(&n, &start); err != nil {
+				return nil, fmt.Errorf("osm: reading node: %w", err)
+			}
+			return &osmpbf.Node{
+				ID:   int64(n.ID),
+				Lat:  n.Lat,
+				Lon:  n.Lon,
+				Tags: n.Tags.Map(),
+			}, nil
+		}
+		w := osmapi.Way{}
+		if err := d.xml.DecodeElement(&w, &start); err != nil {
+			return nil, fmt.Errorf("osm: reading way: %w", err)
+		}
+		ids := make([]int64, len(w.Nodes))
+		for i, n := range w.Nodes {
+			ids[i] = int64(n.ID)
+		}
+		return &osmpbf.Way{
+			ID:      int64(w.ID),
+			Tags:    w.Tags.Map(),
+			NodeIDs: ids,
+		}, nil
+	}
+}
+
+// deleted reports whether an element is marked as deleted by JOSM.
+func deleted(start xml.StartElement) bool {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "action" && attr.Value == "delete" {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *xmlDecoder) Close() error {
+	return d.file.Close()
+}
@@ -0,0 +1,83 @@
+package osm
+
+import (
+	"compress/gzip"
+	"fmt"
+	graph "github.com/JesseleDuran/gograph"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// osmEdges describes the edges of a graph by the OSM IDs of their nodes and
+// ways, so graphs built in different orders can be compared.
+func osmEdges(g graph.Graph) []string {
+	edges := make([]string, 0)
+	for from := range g.Nodes {
+		for _, e := range g.Outgoing(int32(from)) {
+			a, _ := g.OSMNodeID(int32(from))
+			b, _ := g.OSMNodeID(e.ID)
+			ref, _ := g.EdgeWay(e.EdgeID)
+			attr, _ := g.EdgeAttributes(e.EdgeID)
+			edges = append(edges, fmt.Sprintf("%d->%d %.3f class=%d way=%v %v", a, b, e.Weight, e.Class, ref, attr))
+		}
+	}
+	sort.Strings(edges)
+	return edges
+}
+
+// gzipFile writes a gzip compressed copy of a file.
+func gzipFile(t *testing.T, src, dst string) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMakeGraphFromFile_Formats(t *testing.T) {
+	dir := t.TempDir()
+	gzipFile(t, filepath.Join("testdata", "network.osm"), filepath.Join(dir, "network.osm.gz"))
+	gzipFile(t, filepath.Join("testdata", "network.osm.pbf"), filepath.Join(dir, "network.osm.pbf.gz"))
+
+	for _, mode := range []Mode{Driving, Cycling} {
+		filter := Filter{Mode: mode, BarrierPenalty: 60, Attributes: true}
+		filter.Path = filepath.Join("testdata", "network.osm.pbf")
+		pbf := MakeGraphFromFile(filter)
+		expected := osmEdges(pbf)
+		if len(expected) == 0 {
+			t.Fatal("expected edges read from the PBF file")
+		}
+		for _, path := range []string{
+			filepath.Join("testdata", "network.osm"),
+			filepath.Join(dir, "network.osm.gz"),
+			filepath.Join(dir, "network.osm.pbf.gz"),
+		} {
+			filter.Path = path
+			g := MakeGraphFromFile(filter)
+			if edges := osmEdges(g); !reflect.DeepEqual(edges, expected) {
+				t.Fatalf("%s in mode %s: expected the edges of the PBF file\n%v\ngot\n%v", path, mode.ToString(), expected, edges)
+			}
+			if len(g.Nodes) != len(pbf.Nodes) {
+				t.Fatalf("%s: expected %d nodes, got %d", path, len(pbf.Nodes), len(g.Nodes))
+			}
+			// the node 9 and the way 105 are deleted in the XML file.
+			if _, ok := g.NodeByOSMID(9); ok {
+				t.Fatalf("%s: expected the deleted node skipped", path)
+			}
+		}
+	}
+}
@@ -6,8 +6,6 @@ import (
 	"github.com/qedus/osmpbf"
 	"io"
 	"log"
-	"os"
-	"runtime"
 )
 
 const CellLevel = 30
@@ -26,14 +24,42 @@ func (m Mode) ToString() string {
 	return "bike"
 }
 
+// Filter configures the graph creation. Path is an OSM file, either PBF or
+// XML (.osm), optionally compressed with bzip2 or gzip.
 type Filter struct {
 	Path      string
 	Mode      Mode
 	Coverage  s2.Loop
 	SetWeight SetWeight
+	// BarrierPenalty is added to the weight of passing through a barrier that
+	// lets the mode pass, like a gate. It is paid once, by the edges that
+	// enter the barrier node, and is in the unit of the weights.
+	BarrierPenalty float32
+	// DestinationPenalty is added to the weight of each edge of the ways the
+	// mode can only use to reach a place on them, like access=destination, so
+	// the searches do not take them as shortcuts. It is in the unit of the
+	// weights.
+	DestinationPenalty float32
+	// Attributes enables the edge attribute store of the graph.
+	Attributes bool
+	// LargestComponent keeps only the main strongly connected component of
+	// the graph, dropping the islands a search could snap to.
+	LargestComponent bool
 }
 
-type SetWeight func(graph.Coordinate, graph.Coordinate) float32
+// SetWeight computes the weight of the edge built from a way segment.
+type SetWeight func(Segment) float32
+
+// Segment is the piece of a way between two consecutive nodes. It carries the
+// tags of the way and of both nodes so the weight can depend on the road type.
+// Duration is the seconds to travel the segment when the way tells it, like
+// the duration of a ferry route, split by the length of its segments.
+type Segment struct {
+	From, To         graph.Coordinate
+	WayTags          map[string]string
+	FromTags, ToTags map[string]string
+	Duration         float32
+}
 
 func MakeGraphFromFile(filter Filter) graph.Graph {
 	return createGraph(filter)
@@ -46,23 +72,28 @@ func createGraph(filter Filter) graph.Graph {
 	nodes := determineValidNodesFromFile(filter.Path, filter.Mode)
 	log.Println("nodes", len(nodes))
 	nodes = getCoverageNodes(filter.Path, filter.Coverage, nodes)
-
-	f, err := os.Open(filter.Path)
-	if err != nil {
-		log.Fatal(err)
-	}
-
 	log.Println("nodes", len(nodes))
-	g := graph.Graph{Nodes: make([]graph.Node, 0, len(nodes))}
-	d := osmpbf.NewDecoder(f)
-	// use more memory from the start, it is faster
-	d.SetBufferSize(osmpbf.MaxBlobSize)
+	return buildGraphs(filter, []map[int64]int32{nodes})[0]
+}
 
-	// start decoding with several goroutines, it is faster
-	err = d.Start(runtime.GOMAXPROCS(-1))
+// buildGraphs makes a graph for each map of valid nodes in a single pass over
+// the osm file.
+func buildGraphs(filter Filter, nodes []map[int64]int32) []graph.Graph {
+	d, err := openDecoder(filter.Path)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer d.Close()
+
+	graphs := make([]graph.Graph, len(nodes))
+	builders := make([]*builder, len(nodes))
+	for i := range nodes {
+		graphs[i] = graph.Graph{Nodes: make([]graph.Node, 0, len(nodes[i]))}
+		if filter.Attributes {
+			graphs[i].Attributes = graph.NewAttributes()
+		}
+		builders[i] = newBuilder(filter, &graphs[i], nodes[i])
+	}
 	for {
 		if o, err := d.Decode(); err == io.EOF {
 			break
@@ -72,59 +103,31 @@ func createGraph(filter Filter) graph.Graph {
 			switch o := o.(type) {
 
 			case *osmpbf.Node:
-				osmID := o.ID
-				if _, ok := nodes[osmID]; ok {
-					id := g.AddNode(graph.Node{
-						Location: CoordinatesToCellID(o.Lat, o.Lon),
-					})
-					nodes[osmID] = id
+				for i, b := range builders {
+					if _, ok := nodes[i][o.ID]; ok {
+						b.addNode(o)
+					}
 				}
 
 			case *osmpbf.Way:
-				w := o
-				if validWay(*w, filter.Mode) {
-					for i := 0; i < len(w.NodeIDs)-1; i++ {
-						nodeA := graph.Node{}
-						nodeB := graph.Node{}
-						if idA, ok1 := nodes[w.NodeIDs[i]]; ok1 {
-							nodeA = g.Nodes[idA]
-							if idB, ok2 := nodes[w.NodeIDs[i+1]]; ok2 {
-								nodeB = g.Nodes[idB]
-								weight := float32(0.0)
-								if filter.SetWeight == nil {
-									weight = graph.Distance(s2.CellID(nodeA.Location), s2.CellID(nodeB.Location))
-								} else {
-									weight = filter.SetWeight(graph.Coordinate{
-										Lat: s2.CellID(nodeA.Location).LatLng().Lat.Degrees(),
-										Lng: s2.CellID(nodeA.Location).LatLng().Lng.Degrees(),
-									}, graph.Coordinate{
-										Lat: s2.CellID(nodeB.Location).LatLng().Lat.Degrees(),
-										Lng: s2.CellID(nodeB.Location).LatLng().Lng.Degrees(),
-									})
-								}
-								g.RelateNodes(nodeA, nodeB, weight, edgeDirectionFromWay(*w, filter.Mode))
-							}
-						}
-					}
+				for _, b := range builders {
+					b.addWay(o)
 				}
 			}
 		}
 	}
-	nodes = nil
-	return g
+	if filter.LargestComponent {
+		for i := range graphs {
+			removed := graphs[i].KeepLargestComponent()
+			log.Println("removed", removed, "nodes out of the largest component")
+		}
+	}
+	return graphs
 }
 
 // determineValidNodes creates a map of the node of interest.
 func determineValidNodesFromFile(path string, mode Mode) map[int64]int32 {
-	f, err := os.Open(path)
-	if err != nil {
-		log.Fatal(err)
-	}
-	d := osmpbf.NewDecoder(f)
-	// use more memory from the start, it is faster
-	d.SetBufferSize(osmpbf.MaxBlobSize)
-	// start decoding with several goroutines, it is faster
-	err = d.Start(runtime.GOMAXPROCS(-1))
+	d, err := openDecoder(path)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -151,26 +154,28 @@ func determineValidNodesFromFile(path string, mode Mode) map[int64]int32 {
 			}
 		}
 	}
-	_ = f.Close()
+	_ = d.Close()
 	return result
 }
 
 func getCoverageNodes(path string, loop s2.Loop, nodes map[int64]int32) map[int64]int32 {
-	f, err := os.Open(path)
-	if err != nil {
-		log.Fatal(err)
-	}
-	d := osmpbf.NewDecoder(f)
-	// use more memory from the start, it is faster
-	d.SetBufferSize(osmpbf.MaxBlobSize)
-	// start decoding with several goroutines, it is faster
-	err = d.Start(runtime.GOMAXPROCS(-1))
+	return getRegionsNodes(path, []s2.Loop{loop}, nodes)[0]
+}
+
+// getRegionsNodes returns the valid nodes inside each loop. A node can be in
+// several loops when they overlap.
+func getRegionsNodes(path string, loops []s2.Loop, nodes map[int64]int32) []map[int64]int32 {
+	d, err := openDecoder(path)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	result := make(map[int64]int32)
-	i, j := 0, 0
+	result := make([]map[int64]int32, len(loops))
+	bounds := make([]s2.Rect, len(loops))
+	for i := range loops {
+		result[i] = make(map[int64]int32)
+		bounds[i] = loops[i].RectBound()
+	}
 	for {
 		if o, err := d.Decode(); err == io.EOF {
 			break
@@ -180,16 +185,21 @@ func getCoverageNodes(path string, loop s2.Loop, nodes map[int64]int32) map[int6
 			switch o := o.(type) {
 			case *osmpbf.Node:
 				if _, ok := nodes[o.ID]; ok {
-					if loop.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(o.Lat, o.Lon))) {
-						result[o.ID] = int32(i)
-						i++
+					ll := s2.LatLngFromDegrees(o.Lat, o.Lon)
+					for i, loop := range loops {
+						// the bounds discard most of the loops without testing them.
+						if loop.NumVertices() > 0 && !bounds[i].ContainsLatLng(ll) {
+							continue
+						}
+						if inCoverage(loop, o.Lat, o.Lon) {
+							result[i][o.ID] = int32(len(result[i]))
+						}
 					}
 				}
-				j++
 			}
 		}
 	}
-	_ = f.Close()
+	_ = d.Close()
 	return result
 }
 
@@ -210,6 +220,12 @@ func validWay(w osmpbf.Way, mode Mode) bool {
 		"tertiary_link": {}, "residential": {},
 		"unclassified": {}, "living_street": {},
 	}
+	if !wayAccess(w.Tags, mode) {
+		return false
+	}
+	if w.Tags["route"] == "ferry" {
+		return true
+	}
 	_, ok := tags[(w.Tags)["highway"]]
 	if mode == Driving {
 		return ok
@@ -226,22 +242,3 @@ func validWay(w osmpbf.Way, mode Mode) bool {
 	biciOk := okB || ok
 	return biciOk
 }
-
-func edgeDirectionFromWay(w osmpbf.Way, mode Mode) graph.EdgeDirection {
-	tags := w.Tags
-	if mode == Cycling {
-		if cycleway, ok := tags["cycleway"]; ok && cycleway == "opposite" || cycleway == "opposite_track" || cycleway == "opposite_lane" {
-			return graph.Bidirectional
-		}
-		if oneWay, ok := tags["oneway:bicycle"]; ok && oneWay == "no" {
-			return graph.Bidirectional
-		}
-	}
-	if oneWay, ok := tags["oneway"]; ok && oneWay == "yes" {
-		return graph.LeftToRight
-	}
-	if junction, ok := tags["junction"]; ok && junction == "roundabout" {
-		return graph.LeftToRight
-	}
-	return graph.Bidirectional
-}
@@ -1,14 +1,17 @@
 package osm
 
 import (
+	"os"
 	"testing"
 )
 
 func TestMakeGraphFromFile(t *testing.T) {
+	if _, err := os.Stat("colombia.osm.pbf"); err != nil {
+		t.Skip("colombia.osm.pbf is not available")
+	}
 	graph := MakeGraphFromFile(Filter{
-		Path:                  "colombia.osm.pbf",
-		InCoverageGeoJSONPath: "chico.json",
-		Mode:                  0,
+		Path: "colombia.osm.pbf",
+		Mode: 0,
 	})
 	graph.Serialize("chico.gob")
 }
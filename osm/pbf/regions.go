@@ -0,0 +1,55 @@
+package osm
+
+import (
+	"fmt"
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/golang/geo/s2"
+	"log"
+	"path/filepath"
+)
+
+// Region is a named coverage to extract from an OSM file.
+type Region struct {
+	Name     string
+	Coverage s2.Loop
+}
+
+// MakeGraphsFromFile makes a graph for each region of the file, decoding it
+// the same number of times as MakeGraphFromFile does for a single one. The
+// Coverage of the filter is ignored. Regions can overlap, the nodes and ways
+// they share are added to each of their graphs. The graphs are returned by
+// region name, so the names must be unique.
+func MakeGraphsFromFile(filter Filter, regions []Region) (map[string]graph.Graph, error) {
+	names := make(map[string]bool, len(regions))
+	for _, r := range regions {
+		if names[r.Name] {
+			return nil, fmt.Errorf("osm: duplicated region name %q", r.Name)
+		}
+		names[r.Name] = true
+	}
+	nodes := determineValidNodesFromFile(filter.Path, filter.Mode)
+	log.Println("nodes", len(nodes))
+	loops := make([]s2.Loop, len(regions))
+	for i, r := range regions {
+		loops[i] = r.Coverage
+	}
+	regionNodes := getRegionsNodes(filter.Path, loops, nodes)
+	nodes = nil
+	graphs := buildGraphs(filter, regionNodes)
+	result := make(map[string]graph.Graph, len(regions))
+	for i, r := range regions {
+		log.Println(r.Name, "nodes", len(graphs[i].Nodes))
+		result[r.Name] = graphs[i]
+	}
+	return result, nil
+}
+
+// SerializeGraphs writes each graph to dir/<name>.gob.
+func SerializeGraphs(graphs map[string]graph.Graph, dir string) error {
+	for name, g := range graphs {
+		if err := g.Serialize(filepath.Join(dir, name+".gob")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
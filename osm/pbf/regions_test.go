@@ -0,0 +1,50 @@
+package osm
+
+import (
+	"github.com/golang/geo/s2"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// rectLoop returns the loop of a rectangle, in counterclockwise order.
+func rectLoop(minLat, minLng, maxLat, maxLng float64) s2.Loop {
+	return *s2.LoopFromPoints([]s2.Point{
+		s2.PointFromLatLng(s2.LatLngFromDegrees(minLat, minLng)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(minLat, maxLng)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(maxLat, maxLng)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(maxLat, minLng)),
+	})
+}
+
+func TestMakeGraphsFromFile(t *testing.T) {
+	filter := Filter{Path: filepath.Join("testdata", "network.osm"), Mode: Cycling}
+	west := rectLoop(4.5985, -74.0805, 4.6035, -74.0785)
+	east := rectLoop(4.5985, -74.0795, 4.6035, -74.0775)
+	graphs, err := MakeGraphsFromFile(filter, []Region{{Name: "west", Coverage: west}, {Name: "east", Coverage: east}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graphs) != 2 {
+		t.Fatalf("expected 2 graphs, got %d", len(graphs))
+	}
+	// each region matches the graph built for its coverage alone, the nodes 2
+	// and 7 being in both.
+	for name, loop := range map[string]s2.Loop{"west": west, "east": east} {
+		filter.Coverage = loop
+		expected := MakeGraphFromFile(filter)
+		if edges := osmEdges(graphs[name]); !reflect.DeepEqual(edges, osmEdges(expected)) || len(edges) == 0 {
+			t.Fatalf("%s: unexpected edges %v", name, edges)
+		}
+		for _, osmID := range []int64{2, 7} {
+			if _, ok := graphs[name].NodeByOSMID(osmID); !ok {
+				t.Fatalf("%s: expected the shared node %d", name, osmID)
+			}
+		}
+	}
+
+	_, err = MakeGraphsFromFile(filter, []Region{{Name: "west", Coverage: west}, {Name: "west", Coverage: east}})
+	if err == nil {
+		t.Fatal("expected an error on duplicated region names")
+	}
+}
@@ -0,0 +1,169 @@
+package osm
+
+import (
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/golang/geo/s2"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	mphToKmh   = 1.609344
+	knotsToKmh = 1.852
+	// cyclingSpeed is the cruising speed in km/h of a bike on a flat road.
+	cyclingSpeed = 18.0
+	// defaultSpeed is used when nothing is known about the road.
+	defaultSpeed = 30.0
+)
+
+// implicitSpeeds maps the implicit maxspeed values, like "RU:urban", to km/h.
+// A zero speed means there is no limit, as in maxspeed=none.
+var implicitSpeeds = map[string]float64{
+	"AT:urban": 50, "AT:rural": 100, "AT:trunk": 100, "AT:motorway": 130,
+	"CH:urban": 50, "CH:rural": 80, "CH:trunk": 100, "CH:motorway": 120,
+	"CZ:urban": 50, "CZ:rural": 90, "CZ:trunk": 110, "CZ:motorway": 130,
+	"DE:urban": 50, "DE:rural": 100, "DE:living_street": 7, "DE:bicycle_road": 30, "DE:motorway": 0,
+	"ES:urban": 50, "ES:rural": 90, "ES:trunk": 100, "ES:motorway": 120,
+	"FR:urban": 50, "FR:rural": 80, "FR:trunk": 110, "FR:motorway": 130,
+	"GB:nsl_single": 60 * mphToKmh, "GB:nsl_dual": 70 * mphToKmh, "GB:motorway": 70 * mphToKmh,
+	"IT:urban": 50, "IT:rural": 90, "IT:trunk": 110, "IT:motorway": 130,
+	"PL:urban": 50, "PL:rural": 90, "PL:trunk": 120, "PL:motorway": 140,
+	"RU:urban": 60, "RU:rural": 90, "RU:living_street": 20, "RU:motorway": 110,
+	"UA:urban": 50, "UA:rural": 90, "UA:living_street": 20, "UA:motorway": 130,
+}
+
+// highwaySpeeds are the speeds in km/h assumed for each highway class when the
+// way has no usable maxspeed.
+var highwaySpeeds = map[string]float64{
+	"motorway": 110, "motorway_link": 60,
+	"trunk": 90, "trunk_link": 50,
+	"primary": 70, "primary_link": 40,
+	"secondary": 60, "secondary_link": 40,
+	"tertiary": 50, "tertiary_link": 30,
+	"unclassified": 40, "residential": 30,
+	"living_street": 10, "road": 30,
+	"service": 15, "track": 15,
+	"ferry": 20,
+}
+
+// countrySpeeds override highwaySpeeds for a given country code.
+var countrySpeeds = map[string]map[string]float64{
+	"DE": {"motorway": 130, "trunk": 100, "primary": 100, "secondary": 100, "residential": 50},
+	"FR": {"motorway": 130, "trunk": 110, "primary": 80, "secondary": 80, "residential": 50},
+	"GB": {"motorway": 70 * mphToKmh, "trunk": 70 * mphToKmh, "primary": 60 * mphToKmh, "residential": 30 * mphToKmh},
+	"RU": {"motorway": 110, "trunk": 90, "primary": 90, "residential": 60},
+	"US": {"motorway": 65 * mphToKmh, "trunk": 55 * mphToKmh, "primary": 45 * mphToKmh, "residential": 25 * mphToKmh},
+}
+
+// cyclingSpeeds are the speeds in km/h of a bike on the ways it can not use at
+// its cruising speed.
+var cyclingSpeeds = map[string]float64{
+	"track": 12, "path": 10, "footway": 6,
+	"pedestrian": 6, "steps": 2, "ferry": 20,
+}
+
+// ParseMaxSpeed parses an OSM maxspeed value into km/h. It understands plain
+// numbers, "mph" and "knots" units and implicit values like "RU:urban". The
+// value "none" is returned as +Inf.
+func ParseMaxSpeed(value string) (float64, bool) {
+	// multiple values, like "50;30", are resolved to the first one.
+	value = strings.TrimSpace(strings.SplitN(value, ";", 2)[0])
+	if value == "" {
+		return 0, false
+	}
+	if value == "none" {
+		return math.Inf(1), true
+	}
+	if value == "walk" {
+		return 6, true
+	}
+	if kmh, ok := implicitSpeeds[value]; ok {
+		if kmh == 0 {
+			return math.Inf(1), true
+		}
+		return kmh, true
+	}
+	factor := 1.0
+	switch {
+	case strings.HasSuffix(value, "mph"):
+		factor = mphToKmh
+		value = strings.TrimSuffix(value, "mph")
+	case strings.HasSuffix(value, "knots"):
+		factor = knotsToKmh
+		value = strings.TrimSuffix(value, "knots")
+	case strings.HasSuffix(value, "km/h"):
+		value = strings.TrimSuffix(value, "km/h")
+	case strings.HasSuffix(value, "kmh"):
+		value = strings.TrimSuffix(value, "kmh")
+	}
+	speed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || speed <= 0 {
+		return 0, false
+	}
+	return speed * factor, true
+}
+
+// Speed returns the speed in km/h at which the given way is travelled.
+// The maxspeed tag is used when present, otherwise the implicit speed in
+// maxspeed:type or source:maxspeed, and finally the default of the highway
+// class for the country.
+func Speed(tags map[string]string, country string, mode Mode) float64 {
+	highway := tags["highway"]
+	if tags["route"] == "ferry" {
+		highway = "ferry"
+	}
+	fallback := highwaySpeed(highway, country)
+	kmh := 0.0
+	for _, key := range []string{"maxspeed", "maxspeed:type", "source:maxspeed"} {
+		if v, ok := ParseMaxSpeed(tags[key]); ok {
+			kmh = v
+			break
+		}
+	}
+	if kmh == 0 {
+		kmh = fallback
+	}
+	if math.IsInf(kmh, 1) {
+		// there is no limit, assume the road is travelled at the usual speed of
+		// its class, the motorway one at least.
+		kmh = math.Max(fallback, highwaySpeed("motorway", country))
+	}
+	if mode == Cycling {
+		if v, ok := cyclingSpeeds[highway]; ok {
+			return v
+		}
+		return math.Min(kmh, cyclingSpeed)
+	}
+	return kmh
+}
+
+// highwaySpeed returns the default speed of a highway class in a country.
+func highwaySpeed(highway, country string) float64 {
+	if speeds, ok := countrySpeeds[country]; ok {
+		if v, ok := speeds[highway]; ok {
+			return v
+		}
+	}
+	if v, ok := highwaySpeeds[highway]; ok {
+		return v
+	}
+	return defaultSpeed
+}
+
+// TravelTime returns a SetWeight that weights the edges by the seconds needed
+// to traverse them, using the duration of the segment if known or the speed
+// given by Speed. The country is the ISO 3166-1 alpha-2 code used to pick the
+// default speeds, it can be empty.
+func TravelTime(country string, mode Mode) SetWeight {
+	return func(s Segment) float32 {
+		if s.Duration > 0 {
+			return s.Duration
+		}
+		meters := graph.Distance(
+			s2.CellIDFromLatLng(s2.LatLngFromDegrees(s.From.Lat, s.From.Lng)),
+			s2.CellIDFromLatLng(s2.LatLngFromDegrees(s.To.Lat, s.To.Lng)),
+		)
+		return meters / float32(Speed(s.WayTags, country, mode)/3.6)
+	}
+}
@@ -0,0 +1,108 @@
+package osm
+
+import (
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/golang/geo/s2"
+	"math"
+	"testing"
+)
+
+func TestParseMaxSpeed(t *testing.T) {
+	tests := []struct {
+		value string
+		kmh   float64
+		ok    bool
+	}{
+		{"50", 50, true},
+		{" 80 ", 80, true},
+		{"60 km/h", 60, true},
+		{"60kmh", 60, true},
+		{"30 mph", 30 * mphToKmh, true},
+		{"30mph", 30 * mphToKmh, true},
+		{"10 knots", 10 * knotsToKmh, true},
+		{"none", math.Inf(1), true},
+		{"walk", 6, true},
+		{"RU:urban", 60, true},
+		{"DE:motorway", math.Inf(1), true},
+		{"50;30", 50, true},
+		{"30 mph;20 mph", 30 * mphToKmh, true},
+		{"none;50", math.Inf(1), true},
+		{"", 0, false},
+		{"fast", 0, false},
+		{"-5", 0, false},
+		{"0", 0, false},
+		{"mph", 0, false},
+		{"XX:urban", 0, false},
+		{";50", 0, false},
+	}
+	for _, tt := range tests {
+		kmh, ok := ParseMaxSpeed(tt.value)
+		if ok != tt.ok || math.Abs(kmh-tt.kmh) > 1e-9 && !(math.IsInf(kmh, 1) && math.IsInf(tt.kmh, 1)) {
+			t.Errorf("ParseMaxSpeed(%q) = %v, %v, expected %v, %v", tt.value, kmh, ok, tt.kmh, tt.ok)
+		}
+	}
+}
+
+func TestSpeed(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    map[string]string
+		country string
+		mode    Mode
+		kmh     float64
+	}{
+		{"maxspeed", map[string]string{"highway": "primary", "maxspeed": "50"}, "", Driving, 50},
+		{"mph", map[string]string{"highway": "primary", "maxspeed": "40 mph"}, "US", Driving, 40 * mphToKmh},
+		{"highway default", map[string]string{"highway": "primary"}, "", Driving, 70},
+		{"country default", map[string]string{"highway": "primary"}, "DE", Driving, 100},
+		{"country without the class", map[string]string{"highway": "tertiary"}, "DE", Driving, 50},
+		{"unknown highway", map[string]string{"highway": "corridor"}, "", Driving, defaultSpeed},
+		{"malformed maxspeed", map[string]string{"highway": "residential", "maxspeed": "slow"}, "", Driving, 30},
+		{"maxspeed type", map[string]string{"highway": "primary", "maxspeed:type": "FR:rural"}, "", Driving, 80},
+		{"source maxspeed", map[string]string{"highway": "primary", "source:maxspeed": "RU:urban"}, "", Driving, 60},
+		{"maxspeed over type", map[string]string{"highway": "primary", "maxspeed": "30", "maxspeed:type": "FR:rural"}, "", Driving, 30},
+		{"none", map[string]string{"highway": "motorway", "maxspeed": "none"}, "DE", Driving, 130},
+		{"none on a slow road", map[string]string{"highway": "residential", "maxspeed": "none"}, "", Driving, 110},
+		{"walk", map[string]string{"highway": "living_street", "maxspeed": "walk"}, "", Driving, 6},
+		{"list", map[string]string{"highway": "primary", "maxspeed": "60;40"}, "", Driving, 60},
+		{"ferry", map[string]string{"route": "ferry"}, "", Driving, 20},
+		{"ferry knots", map[string]string{"route": "ferry", "maxspeed": "10 knots"}, "", Driving, 10 * knotsToKmh},
+		{"cycling capped", map[string]string{"highway": "primary"}, "", Cycling, cyclingSpeed},
+		{"cycling slow road", map[string]string{"highway": "living_street", "maxspeed": "walk"}, "", Cycling, 6},
+		{"cycling track", map[string]string{"highway": "track", "maxspeed": "50"}, "", Cycling, 12},
+		{"cycling ferry", map[string]string{"route": "ferry"}, "", Cycling, 20},
+	}
+	for _, tt := range tests {
+		if kmh := Speed(tt.tags, tt.country, tt.mode); math.Abs(kmh-tt.kmh) > 1e-9 {
+			t.Errorf("%s: expected %v km/h, got %v", tt.name, tt.kmh, kmh)
+		}
+	}
+}
+
+func TestTravelTime(t *testing.T) {
+	from := graph.Coordinate{Lat: 4.6, Lng: -74.08}
+	to := graph.Coordinate{Lat: 4.6, Lng: -74.07}
+	meters := float64(graph.Distance(
+		s2.CellIDFromLatLng(s2.LatLngFromDegrees(from.Lat, from.Lng)),
+		s2.CellIDFromLatLng(s2.LatLngFromDegrees(to.Lat, to.Lng)),
+	))
+	tests := []struct {
+		name     string
+		weight   SetWeight
+		tags     map[string]string
+		duration float32
+		seconds  float64
+	}{
+		{"maxspeed", TravelTime("", Driving), map[string]string{"highway": "primary", "maxspeed": "36"}, 0, meters / 10},
+		{"mph", TravelTime("", Driving), map[string]string{"highway": "primary", "maxspeed": "36 mph"}, 0, meters / (36 * mphToKmh / 3.6)},
+		{"default", TravelTime("US", Driving), map[string]string{"highway": "residential"}, 0, meters / (25 * mphToKmh / 3.6)},
+		{"cycling", TravelTime("", Cycling), map[string]string{"highway": "primary", "maxspeed": "50"}, 0, meters / 5},
+		{"duration", TravelTime("", Driving), map[string]string{"route": "ferry"}, 600, 600},
+	}
+	for _, tt := range tests {
+		seconds := tt.weight(Segment{From: from, To: to, WayTags: tt.tags, Duration: tt.duration})
+		if math.Abs(float64(seconds)-tt.seconds) > 1e-3 {
+			t.Errorf("%s: expected %v s, got %v", tt.name, tt.seconds, seconds)
+		}
+	}
+}
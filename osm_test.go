@@ -0,0 +1,76 @@
+package gograph
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGraph_OSMRefs(t *testing.T) {
+	g := testGraph()
+	for id, osmID := range []int64{100, 101, 102, 103} {
+		g.SetOSMNode(int32(id), osmID)
+	}
+	// two ways relating the nodes 0 and 1, the second one added as a
+	// parallel edge.
+	g.SetEdgeWay(0, WayRef{ID: 10, Position: 0})
+	g.SetEdgeWay(1, WayRef{ID: 10, Position: 0, Reverse: true})
+	parallel := g.AddEdge(0, 1, 2, 0)
+	g.SetEdgeWay(parallel, WayRef{ID: 20, Position: 3})
+
+	if osmID, ok := g.OSMNodeID(2); !ok || osmID != 102 {
+		t.Fatalf("unexpected OSM node %d", osmID)
+	}
+	if _, ok := g.OSMNodeID(9); ok {
+		t.Fatal("expected no OSM node out of the graph")
+	}
+	if id, ok := g.NodeByOSMID(103); !ok || id != 3 {
+		t.Fatalf("unexpected node %d", id)
+	}
+	if ref, ok := g.EdgeWay(0); !ok || ref.ID != 10 || ref.Reverse {
+		t.Fatalf("unexpected way %v", ref)
+	}
+	if ref, ok := g.EdgeWay(parallel); !ok || ref.ID != 20 || ref.Position != 3 {
+		t.Fatalf("expected the parallel edge to keep its way, got %v", ref)
+	}
+	if ref, _ := g.EdgeWay(1); !ref.Reverse {
+		t.Fatal("expected the reverse edge of the way")
+	}
+
+	// the references survive the serialization.
+	var b bytes.Buffer
+	if _, err := g.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	r := Graph{}
+	if _, err := r.ReadFrom(&b); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := r.NodeByOSMID(101); id != 1 {
+		t.Fatalf("expected the OSM nodes read, got %d", id)
+	}
+	if ref, _ := r.EdgeWay(parallel); ref.ID != 20 {
+		t.Fatalf("expected the ways read, got %v", ref)
+	}
+
+	// the node 0 is removed with its edges and the others are reversed.
+	g.SetEdgeWay(2, WayRef{ID: 30})
+	g.Renumber([]int32{-1, 2, 1, 0})
+	if _, ok := g.NodeByOSMID(100); ok {
+		t.Fatal("expected the OSM node of the removed node dropped")
+	}
+	if id, _ := g.NodeByOSMID(101); id != 2 {
+		t.Fatalf("expected the OSM node renumbered, got %d", id)
+	}
+	if osmID, _ := g.OSMNodeID(0); osmID != 103 {
+		t.Fatalf("unexpected OSM node %d", osmID)
+	}
+	if _, ok := g.EdgeWay(parallel); ok {
+		t.Fatal("expected the way of the removed parallel edge dropped")
+	}
+	if _, ok := g.EdgeWay(0); ok {
+		t.Fatal("expected the way of the removed edge dropped")
+	}
+	if ref, _ := g.EdgeWay(2); ref.ID != 30 {
+		t.Fatalf("expected the way of the edge kept, got %v", ref)
+	}
+}
@@ -0,0 +1,456 @@
+package gograph
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// A serialized patch starts with the magic "GOGRAPHP" and the format version
+// uint32, little-endian, followed by the patch gob encoded and gzip
+// compressed.
+const (
+	patchMagic   = "GOGRAPHP"
+	patchVersion = 3
+)
+
+var (
+	// ErrNotPatch is returned when reading something that is not a patch.
+	ErrNotPatch = errors.New("gograph: not a serialized patch")
+	// ErrPatch is returned when a patch does not apply to a graph, because it
+	// was made from a different version of it.
+	ErrPatch = errors.New("gograph: the patch does not apply to the graph")
+)
+
+// NodeKey identifies a node in different versions of a graph: by its OSM ID
+// when the graph has it, so the node can move, or else by its location.
+type NodeKey struct {
+	OSMID    int64
+	Location uint64
+}
+
+// NodePair identifies the nodes related by an edge.
+type NodePair struct {
+	From, To NodeKey
+}
+
+// PatchNode holds the new values of a node.
+type PatchNode struct {
+	Key        NodeKey
+	Location   uint64
+	Data       []uint64
+	Compressed bool
+	Elevation  float32
+}
+
+// PatchEdge holds the new values of an edge, identified by its nodes and OSM
+// way, see Diff. ID is the EdgeID of the edge in the graph the patch applies
+// to, or in the new graph for the edges added. Way and Attributes are nil when
+// the edge has none.
+type PatchEdge struct {
+	ID EdgeID
+	NodePair
+	Weight     float32
+	Class      EdgeClass
+	Way        *WayRef
+	Attributes *EdgeAttributes
+}
+
+// Patch holds the changes between two versions of a graph, see Diff. The
+// edges of the removed nodes are not listed, they are removed with them.
+type Patch struct {
+	AddedNodes   []PatchNode
+	ChangedNodes []PatchNode
+	RemovedNodes []NodeKey
+	AddedEdges   []PatchEdge
+	ChangedEdges []PatchEdge
+	RemovedEdges []EdgeID
+	// Elevation is set when the new graph has elevations.
+	Elevation bool
+}
+
+// Len returns the number of nodes and edges changed.
+func (p Patch) Len() int {
+	return len(p.AddedNodes) + len(p.ChangedNodes) + len(p.RemovedNodes) +
+		len(p.AddedEdges) + len(p.ChangedEdges) + len(p.RemovedEdges)
+}
+
+// Diff returns the changes that turn the graph before into after, see Apply.
+// The nodes are matched by NodeKey and the edges by the nodes they relate, so
+// the graphs can come from different imports, whose EdgeIDs do not match. The
+// parallel edges are told apart by their OSM way, see matchEdges. The nodes
+// sharing a key are matched with the first of them.
+func Diff(before, after Graph) Patch {
+	p := Patch{Elevation: after.Elevation != nil}
+	oldNodes, newNodes := before.nodeKeys(), after.nodeKeys()
+
+	for id, key := range after.nodeKeyList() {
+		n := after.patchNode(int32(id), key)
+		oldID, ok := oldNodes[key]
+		switch {
+		case newNodes[key] != int32(id):
+			// a duplicated key, only the first node is matched.
+		case !ok:
+			p.AddedNodes = append(p.AddedNodes, n)
+		case !n.equal(before.patchNode(oldID, key), p.Elevation):
+			p.ChangedNodes = append(p.ChangedNodes, n)
+		}
+	}
+	for id, key := range before.nodeKeyList() {
+		if _, ok := newNodes[key]; !ok && oldNodes[key] == int32(id) {
+			p.RemovedNodes = append(p.RemovedNodes, key)
+		}
+	}
+
+	oldEdges, newEdges := before.patchEdges(), after.patchEdges()
+	matched := make([]bool, len(oldEdges))
+	for i, old := range matchEdges(oldEdges, newEdges) {
+		e := newEdges[i]
+		switch {
+		case old < 0:
+			p.AddedEdges = append(p.AddedEdges, e)
+		case !e.equal(oldEdges[old]):
+			e.ID = oldEdges[old].ID
+			p.ChangedEdges = append(p.ChangedEdges, e)
+		}
+		if old >= 0 {
+			matched[old] = true
+		}
+	}
+	for i, old := range oldEdges {
+		_, fromKept := newNodes[old.From]
+		_, toKept := newNodes[old.To]
+		if !matched[i] && fromKept && toKept {
+			p.RemovedEdges = append(p.RemovedEdges, old.ID)
+		}
+	}
+	return p
+}
+
+// matchEdges returns, for each new edge, the index of the old edge relating
+// the same nodes it matches, -1 for the edges added. The parallel edges are
+// matched first by their whole OSM way reference, then by the way ID alone,
+// so an edge whose way gained nodes before it keeps matching, and the rest in
+// the order of their IDs.
+func matchEdges(oldEdges, newEdges []PatchEdge) []int {
+	byPair := make(map[NodePair][]int, len(oldEdges))
+	for i, e := range oldEdges {
+		byPair[e.NodePair] = append(byPair[e.NodePair], i)
+	}
+	rules := []func(a, b PatchEdge) bool{
+		func(a, b PatchEdge) bool {
+			return (a.Way == nil) == (b.Way == nil) && (a.Way == nil || *a.Way == *b.Way)
+		},
+		func(a, b PatchEdge) bool {
+			return a.Way != nil && b.Way != nil && a.Way.ID == b.Way.ID
+		},
+		func(a, b PatchEdge) bool { return true },
+	}
+	match := make([]int, len(newEdges))
+	for i := range match {
+		match[i] = -1
+	}
+	used := make([]bool, len(oldEdges))
+	for _, same := range rules {
+		for i, e := range newEdges {
+			if match[i] >= 0 {
+				continue
+			}
+			for _, old := range byPair[e.NodePair] {
+				if !used[old] && same(oldEdges[old], e) {
+					match[i], used[old] = old, true
+					break
+				}
+			}
+		}
+	}
+	return match
+}
+
+// Apply changes the graph with a patch made by Diff from it. The nodes kept
+// keep their order and the nodes added follow them, so the graph ends equal
+// to the one given to Diff up to the IDs of its nodes and edges: the edges
+// kept keep their EdgeIDs and the edges added get new ones. Nothing is changed
+// when the patch does not apply, and an error wrapping ErrPatch is returned.
+// A frozen graph is thawed to apply the patch and frozen again.
+func (g *Graph) Apply(p Patch) error {
+	if err := g.checkPatch(p); err != nil {
+		return err
+	}
+	if g.IsFrozen() {
+		g.Thaw()
+		defer g.Freeze()
+	}
+	if !p.Elevation {
+		g.Elevation = nil
+	} else if g.Elevation == nil {
+		g.Elevation = make([]float32, len(g.Nodes))
+		for i := range g.Elevation {
+			g.Elevation[i] = float32(math.NaN())
+		}
+	}
+
+	nodes := g.nodeKeys()
+	for _, n := range p.ChangedNodes {
+		id := nodes[n.Key]
+		g.Nodes[id].Location = n.Location
+		g.Nodes[id].Data = append([]uint64(nil), n.Data...)
+		g.Nodes[id].Compressed = n.Compressed
+		if p.Elevation {
+			g.Elevation[id] = n.Elevation
+		}
+	}
+	for _, id := range p.RemovedEdges {
+		g.RemoveEdgeByID(id)
+	}
+	for _, e := range p.ChangedEdges {
+		g.setPatchEdge(e)
+	}
+
+	if len(p.RemovedNodes) > 0 {
+		ids := make([]int32, len(g.Nodes))
+		for _, key := range p.RemovedNodes {
+			ids[nodes[key]] = -1
+		}
+		next := int32(0)
+		for i := range ids {
+			if ids[i] == 0 {
+				ids[i] = next
+				next++
+			}
+		}
+		g.Renumber(ids)
+		nodes = g.nodeKeys()
+	}
+
+	for _, n := range p.AddedNodes {
+		id := g.AddNode(Node{
+			Location:   n.Location,
+			Data:       append([]uint64(nil), n.Data...),
+			Compressed: n.Compressed,
+		})
+		if n.Key.OSMID != 0 {
+			g.SetOSMNode(id, n.Key.OSMID)
+		}
+		if p.Elevation {
+			g.Elevation[id] = n.Elevation
+		}
+		nodes[n.Key] = id
+	}
+	for _, e := range p.AddedEdges {
+		e.ID = g.AddEdge(nodes[e.From], nodes[e.To], e.Weight, e.Class)
+		g.setPatchEdge(e)
+	}
+	g.EdgeIndex = g.BuildEdgeIndex()
+	return nil
+}
+
+// checkPatch checks that the nodes and edges the patch changes exist, and
+// that the nodes it adds do not.
+func (g Graph) checkPatch(p Patch) error {
+	nodes := g.nodeKeys()
+	removed := make(map[NodeKey]bool, len(p.RemovedNodes))
+	for _, key := range p.RemovedNodes {
+		if _, ok := nodes[key]; !ok {
+			return fmt.Errorf("%w: removed node %v not found", ErrPatch, key)
+		}
+		removed[key] = true
+	}
+	for _, n := range p.ChangedNodes {
+		if _, ok := nodes[n.Key]; !ok {
+			return fmt.Errorf("%w: changed node %v not found", ErrPatch, n.Key)
+		}
+	}
+	added := make(map[NodeKey]bool, len(p.AddedNodes))
+	for _, n := range p.AddedNodes {
+		if _, ok := nodes[n.Key]; ok || added[n.Key] {
+			return fmt.Errorf("%w: added node %v already exists", ErrPatch, n.Key)
+		}
+		added[n.Key] = true
+	}
+
+	for _, id := range p.RemovedEdges {
+		if _, ok := g.EdgeByID(id); !ok {
+			return fmt.Errorf("%w: removed edge %d not found", ErrPatch, id)
+		}
+	}
+	for _, e := range p.ChangedEdges {
+		ends, ok := g.EdgeByID(e.ID)
+		if !ok || g.nodeKey(ends.From) != e.From || g.nodeKey(ends.To) != e.To {
+			return fmt.Errorf("%w: edge %d %v -> %v not found", ErrPatch, e.ID, e.From, e.To)
+		}
+	}
+	for _, e := range p.AddedEdges {
+		for _, key := range [2]NodeKey{e.From, e.To} {
+			if _, ok := nodes[key]; (!ok || removed[key]) && !added[key] {
+				return fmt.Errorf("%w: node %v of an added edge not found", ErrPatch, key)
+			}
+		}
+	}
+	return nil
+}
+
+// nodeKey returns the key of a node.
+func (g Graph) nodeKey(id int32) NodeKey {
+	if osmID, ok := g.OSMNodeID(id); ok {
+		return NodeKey{OSMID: osmID}
+	}
+	return NodeKey{Location: g.Nodes[id].Location}
+}
+
+// nodeKeyList returns the key of each node, indexed by node ID.
+func (g Graph) nodeKeyList() []NodeKey {
+	keys := make([]NodeKey, len(g.Nodes))
+	for i := range g.Nodes {
+		keys[i] = g.nodeKey(int32(i))
+	}
+	return keys
+}
+
+// nodeKeys returns the first node of each key.
+func (g Graph) nodeKeys() map[NodeKey]int32 {
+	nodes := make(map[NodeKey]int32, len(g.Nodes))
+	for i := len(g.Nodes) - 1; i >= 0; i-- {
+		nodes[g.nodeKey(int32(i))] = int32(i)
+	}
+	return nodes
+}
+
+func (g Graph) patchNode(id int32, key NodeKey) PatchNode {
+	n := g.Nodes[id]
+	p := PatchNode{Key: key, Location: n.Location, Data: n.Data, Compressed: n.Compressed}
+	if g.Elevation != nil {
+		p.Elevation = float32(math.NaN())
+		if e, ok := g.NodeElevation(id); ok {
+			p.Elevation = e
+		}
+	}
+	return p
+}
+
+func (n PatchNode) equal(o PatchNode, elevation bool) bool {
+	if n.Location != o.Location || n.Compressed != o.Compressed || len(n.Data) != len(o.Data) {
+		return false
+	}
+	// the missing elevations are NaN, which is not equal to itself.
+	missing := math.IsNaN(float64(n.Elevation)) && math.IsNaN(float64(o.Elevation))
+	if elevation && n.Elevation != o.Elevation && !missing {
+		return false
+	}
+	for i := range n.Data {
+		if n.Data[i] != o.Data[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// patchEdges returns the edges of the graph in the order of their IDs.
+func (g Graph) patchEdges() []PatchEdge {
+	keys := g.nodeKeyList()
+	nodes := g.nodeKeys()
+	edges := make([]PatchEdge, 0, len(g.EdgeEnds))
+	for id := range g.EdgeEnds {
+		from, e, ok := g.EdgeOf(EdgeID(id))
+		if !ok {
+			continue
+		}
+		pair := NodePair{From: keys[from], To: keys[e.ID]}
+		if nodes[pair.From] != from || nodes[pair.To] != e.ID {
+			continue
+		}
+		p := PatchEdge{ID: e.EdgeID, NodePair: pair, Weight: e.Weight, Class: e.Class}
+		if ref, ok := g.EdgeWay(e.EdgeID); ok {
+			p.Way = &ref
+		}
+		if attr, ok := g.EdgeAttributes(e.EdgeID); ok {
+			p.Attributes = &attr
+		}
+		edges = append(edges, p)
+	}
+	return edges
+}
+
+func (e PatchEdge) equal(o PatchEdge) bool {
+	if e.Weight != o.Weight || e.Class != o.Class {
+		return false
+	}
+	if (e.Way == nil) != (o.Way == nil) || e.Way != nil && *e.Way != *o.Way {
+		return false
+	}
+	return (e.Attributes == nil) == (o.Attributes == nil) &&
+		(e.Attributes == nil || *e.Attributes == *o.Attributes)
+}
+
+// setPatchEdge sets the values of an edge.
+func (g *Graph) setPatchEdge(e PatchEdge) {
+	g.SetEdgeWeightByID(e.ID, e.Weight)
+	ends, _ := g.EdgeByID(e.ID)
+	for _, edges := range [2][]Edge{g.Outgoing(ends.From), g.Incoming(ends.To)} {
+		for i := range edges {
+			if edges[i].EdgeID == e.ID {
+				edges[i].Class = e.Class
+			}
+		}
+	}
+	if e.Way != nil {
+		g.SetEdgeWay(e.ID, *e.Way)
+	} else {
+		g.SetEdgeWay(e.ID, WayRef{})
+	}
+	if e.Attributes != nil {
+		if g.Attributes == nil {
+			g.Attributes = NewAttributes()
+		}
+		g.Attributes.Set(e.ID, *e.Attributes)
+	} else {
+		g.Attributes.unset(e.ID)
+	}
+}
+
+// WriteTo writes the patch to w, see ReadFrom to read it back.
+func (p Patch) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	header := make([]byte, len(patchMagic)+4)
+	copy(header, patchMagic)
+	binary.LittleEndian.PutUint32(header[len(patchMagic):], patchVersion)
+	if _, err := cw.Write(header); err != nil {
+		return cw.n, err
+	}
+	zw := gzip.NewWriter(cw)
+	if err := gob.NewEncoder(zw).Encode(p); err != nil {
+		return cw.n, fmt.Errorf("gograph: encoding patch: %w", err)
+	}
+	err := zw.Close()
+	return cw.n, err
+}
+
+// ReadFrom replaces the patch by the one read from r, written by WriteTo.
+func (p *Patch) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	header := make([]byte, len(patchMagic)+4)
+	if _, err := io.ReadFull(cr, header); err != nil {
+		return cr.n, fmt.Errorf("%w: %v", ErrNotPatch, err)
+	}
+	if string(header[:len(patchMagic)]) != patchMagic {
+		return cr.n, ErrNotPatch
+	}
+	if v := binary.LittleEndian.Uint32(header[len(patchMagic):]); v != patchVersion {
+		return cr.n, fmt.Errorf("%w %d, expected version %d", ErrVersion, v, patchVersion)
+	}
+	zr, err := gzip.NewReader(cr)
+	if err != nil {
+		return cr.n, fmt.Errorf("gograph: decompressing patch: %w", err)
+	}
+	defer zr.Close()
+	decoded := Patch{}
+	if err := gob.NewDecoder(zr).Decode(&decoded); err != nil {
+		return cr.n, fmt.Errorf("gograph: decoding patch: %w", err)
+	}
+	*p = decoded
+	return cr.n, nil
+}
@@ -0,0 +1,130 @@
+package gograph
+
+import (
+	"bytes"
+	"errors"
+	"github.com/golang/geo/s2"
+	"testing"
+)
+
+func TestDiff_Apply(t *testing.T) {
+	before := testGraph()
+	before.SetOSMNode(1, 100)
+	before.SetOSMNode(2, 200)
+	before.Elevation = []float32{0, 1, 2, 3}
+	before.Attributes = NewAttributes()
+	before.Attributes.Set(0, EdgeAttributes{Name: "Calle 26"})
+
+	after := testGraph()
+	after.SetOSMNode(1, 100)
+	after.SetOSMNode(2, 200)
+	after.Elevation = []float32{0, 1, 2, 3}
+	after.Attributes = NewAttributes()
+	// the OSM node 200 moves, the edge 0 -> 1 is renamed and 1 -> 2 is slower.
+	after.Nodes[2].Location = uint64(s2.CellIDFromLatLng(s2.LatLngFromDegrees(4.6005, -74.078)))
+	after.Attributes.Set(0, EdgeAttributes{Name: "Avenida 26"})
+	after.SetEdgeWeight(1, 2, 4)
+	// the node 3 is removed and a new one is related with 2.
+	after.DeleteRelations(3)
+	after.Renumber([]int32{0, 1, 2, -1})
+	id := after.AddNode(Node{Location: uint64(s2.CellIDFromLatLng(s2.LatLngFromDegrees(4.61, -74.078))), Data: []uint64{7}})
+	after.Elevation[id] = 9
+	after.RelateNodes(after.Nodes[2], after.Nodes[id], 2, Bidirectional)
+	after.RemoveEdge(1, 0)
+
+	p := Diff(before, after)
+	if len(p.AddedNodes) != 1 || len(p.ChangedNodes) != 1 || len(p.RemovedNodes) != 1 {
+		t.Fatalf("unexpected nodes %+v", p)
+	}
+	if len(p.AddedEdges) != 2 || len(p.ChangedEdges) != 2 || len(p.RemovedEdges) != 1 {
+		t.Fatalf("unexpected edges %+v", p)
+	}
+
+	buf := &bytes.Buffer{}
+	if _, err := p.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	read := Patch{}
+	if _, err := read.ReadFrom(buf); err != nil {
+		t.Fatal(err)
+	}
+	g := before
+	g.Freeze()
+	if err := g.Apply(read); err != nil {
+		t.Fatal(err)
+	}
+	if d := Diff(g, after); d.Len() != 0 {
+		t.Fatalf("expected the graphs equal, got %+v", d)
+	}
+	if !g.IsFrozen() || len(g.Nodes) != 4 || g.Elevation[3] != 9 {
+		t.Fatalf("unexpected graph %v %v", g.Nodes, g.Elevation)
+	}
+	if cost := g.Dijkstra(ShortestPathCriteria{From: 0, To: 3}); cost != 7 {
+		t.Fatalf("expected cost 7, got %f", cost)
+	}
+	if err := g.Apply(read); !errors.Is(err, ErrPatch) {
+		t.Fatalf("expected ErrPatch applying twice, got %v", err)
+	}
+	if d := Diff(g, after); d.Len() != 0 {
+		t.Fatal("expected the graph unchanged by a patch that does not apply")
+	}
+	if _, err := read.ReadFrom(bytes.NewReader([]byte("GOGRAPHG"))); !errors.Is(err, ErrNotPatch) {
+		t.Fatalf("expected ErrNotPatch, got %v", err)
+	}
+}
+
+func TestDiff_Apply_ParallelEdges(t *testing.T) {
+	before := testGraph()
+	before.Attributes = NewAttributes()
+	before.SetEdgeWay(0, WayRef{ID: 10})
+	before.SetEdgeWay(2, WayRef{ID: 20})
+	// a toll road runs along the edge 0 -> 1.
+	toll := before.AddEdge(0, 1, 3, ClassToll)
+	before.SetEdgeWay(toll, WayRef{ID: 30})
+	before.Attributes.Set(toll, EdgeAttributes{Name: "Autopista"})
+
+	// the same roads imported again, so the EdgeIDs do not match: the toll
+	// road gets the ID 6 and the edge 0 -> 1 the ID 7.
+	after := testGraph()
+	after.Attributes = NewAttributes()
+	after.SetEdgeWay(2, WayRef{ID: 20})
+	afterToll := after.AddEdge(0, 1, 2, ClassToll)
+	after.SetEdgeWay(afterToll, WayRef{ID: 30})
+	after.Attributes.Set(afterToll, EdgeAttributes{Name: "Autopista Norte"})
+	after.RemoveEdgeByID(0)
+	after.SetEdgeWay(after.AddEdge(0, 1, 1, 0), WayRef{ID: 10})
+	// a second road runs along 1 -> 2, and the edge 2 -> 1 is removed.
+	second := after.AddEdge(1, 2, 6, 0)
+	after.SetEdgeWay(second, WayRef{ID: 40})
+	after.RemoveEdgeByID(3)
+
+	p := Diff(before, after)
+	if len(p.AddedEdges) != 1 || p.AddedEdges[0].ID != second ||
+		len(p.ChangedEdges) != 1 || p.ChangedEdges[0].ID != toll ||
+		len(p.RemovedEdges) != 1 || p.RemovedEdges[0] != 3 {
+		t.Fatalf("unexpected edges %+v", p)
+	}
+	g := before
+	if err := g.Apply(p); err != nil {
+		t.Fatal(err)
+	}
+	if d := Diff(g, after); d.Len() != 0 {
+		t.Fatalf("expected the graphs equal, got %+v", d)
+	}
+	// the parallel edges keep their own values and IDs.
+	if _, e, _ := g.EdgeOf(toll); e.Weight != 2 {
+		t.Fatalf("unexpected toll edge %v", e)
+	}
+	if attr, _ := g.EdgeAttributes(toll); attr.Name != "Autopista Norte" {
+		t.Fatalf("unexpected attributes %+v", attr)
+	}
+	if _, ok := g.EdgeAttributes(0); ok {
+		t.Fatal("expected the edge 0 without attributes")
+	}
+	if ref, _ := g.EdgeWay(EdgeID(len(g.EdgeEnds) - 1)); ref.ID != 40 {
+		t.Fatalf("expected the edge added with a new ID, got the way %v", ref)
+	}
+	if err := g.Apply(p); !errors.Is(err, ErrPatch) {
+		t.Fatalf("expected ErrPatch applying twice, got %v", err)
+	}
+}
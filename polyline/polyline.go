@@ -0,0 +1,97 @@
+// Package polyline encodes and decodes the Google encoded polyline format,
+// a compact text form of a line used by most web and mobile map clients.
+//
+// The coordinates are [lng, lat] pairs, as in the geometry of the paths and
+// in GeoJSON, though the format stores the latitude first.
+package polyline
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	// Precision5 is the precision of the Google Maps polylines, about a meter.
+	Precision5 = 5
+	// Precision6 is the precision of the OSRM and Valhalla polylines, about
+	// ten centimeters.
+	Precision6 = 6
+)
+
+// ErrInvalid is returned when decoding a string that is not a polyline.
+var ErrInvalid = errors.New("polyline: invalid encoded polyline")
+
+// Encode returns the polyline of the [lng, lat] coordinates with the given
+// number of decimals.
+func Encode(coordinates [][]float64, precision int) string {
+	factor := math.Pow(10, float64(precision))
+	buf := make([]byte, 0, len(coordinates)*8)
+	var lastLat, lastLng int64
+	for _, c := range coordinates {
+		lat, lng := int64(math.Round(c[1]*factor)), int64(math.Round(c[0]*factor))
+		buf = appendValue(buf, lat-lastLat)
+		buf = appendValue(buf, lng-lastLng)
+		lastLat, lastLng = lat, lng
+	}
+	return string(buf)
+}
+
+// appendValue appends a signed value in chunks of 5 bits, from the lowest,
+// each offset by 63 to be a printable character and ORed with 0x20 when
+// another chunk follows.
+func appendValue(buf []byte, v int64) []byte {
+	u := uint64(v) << 1
+	if v < 0 {
+		u = ^u
+	}
+	for u >= 0x20 {
+		buf = append(buf, byte(0x20|u&0x1f)+63)
+		u >>= 5
+	}
+	return append(buf, byte(u)+63)
+}
+
+// Decode returns the [lng, lat] coordinates of a polyline encoded with the
+// given number of decimals.
+func Decode(s string, precision int) ([][]float64, error) {
+	factor := math.Pow(10, float64(precision))
+	coordinates := make([][]float64, 0, len(s)/8)
+	var lat, lng int64
+	for i := 0; i < len(s); {
+		dlat, n, err := readValue(s[i:])
+		if err != nil {
+			return nil, err
+		}
+		i += n
+		dlng, n, err := readValue(s[i:])
+		if err != nil {
+			return nil, err
+		}
+		i += n
+		lat, lng = lat+dlat, lng+dlng
+		coordinates = append(coordinates, []float64{float64(lng) / factor, float64(lat) / factor})
+	}
+	return coordinates, nil
+}
+
+// readValue reads a value written by appendValue, and returns the number of
+// bytes read.
+func readValue(s string) (int64, int, error) {
+	var u uint64
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if b < 63 || b > 63+0x3f || i >= 13 {
+			return 0, 0, ErrInvalid
+		}
+		b -= 63
+		u |= uint64(b&0x1f) << (5 * i)
+		if b&0x20 == 0 {
+			v := int64(u >> 1)
+			if u&1 != 0 {
+				v = ^v
+			}
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, ErrInvalid
+}
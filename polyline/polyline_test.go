@@ -0,0 +1,41 @@
+package polyline
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	// the example of the format documentation.
+	coordinates := [][]float64{{-120.2, 38.5}, {-120.95, 40.7}, {-126.453, 43.252}}
+	if s := Encode(coordinates, Precision5); s != "_p~iF~ps|U_ulLnnqC_mqNvxq`@" {
+		t.Fatalf("unexpected polyline %s", s)
+	}
+	if s := Encode(nil, Precision5); s != "" {
+		t.Fatalf("expected an empty polyline, got %s", s)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	coordinates := [][]float64{{-74.080001, 4.600002}, {-74.079, 4.6}, {-74.0785, 4.612345}, {0, 0}, {179.999999, -89.999999}}
+	for _, precision := range []int{Precision5, Precision6} {
+		decoded, err := Decode(Encode(coordinates, precision), precision)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(decoded) != len(coordinates) {
+			t.Fatalf("expected %d coordinates, got %d", len(coordinates), len(decoded))
+		}
+		tolerance := math.Pow(10, -float64(precision)) / 2
+		for i, c := range coordinates {
+			if math.Abs(decoded[i][0]-c[0]) > tolerance || math.Abs(decoded[i][1]-c[1]) > tolerance {
+				t.Fatalf("precision %d: expected %v, got %v", precision, c, decoded[i])
+			}
+		}
+	}
+	for _, s := range []string{"_p~iF~ps|U_", "_p~iF", " ", "~~~~~~~~~~~~~~~"} {
+		if _, err := Decode(s, Precision5); err != ErrInvalid {
+			t.Fatalf("expected ErrInvalid decoding %q, got %v", s, err)
+		}
+	}
+}
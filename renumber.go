@@ -0,0 +1,170 @@
+package gograph
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Order is a way to sort the nodes of a graph.
+type Order int
+
+const (
+	// HilbertOrder sorts the nodes by their location, the S2 cell IDs follow
+	// a Hilbert curve so close nodes get close IDs.
+	HilbertOrder Order = iota
+	// BFSOrder sorts the nodes as a breadth first search visits them, from
+	// the node 0, ignoring the direction of the edges.
+	BFSOrder
+)
+
+// Reorder renumbers the nodes in the given order, so the nodes a search
+// visits together are close in memory. It returns the new ID of each node,
+// indexed by its old ID, to remap the references kept out of the graph. An
+// unknown order is an error and the graph is not changed.
+func (g *Graph) Reorder(order Order) ([]int32, error) {
+	sorted := make([]int32, 0, len(g.Nodes))
+	switch order {
+	case HilbertOrder:
+		for i := range g.Nodes {
+			sorted = append(sorted, int32(i))
+		}
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return g.Nodes[sorted[i]].Location < g.Nodes[sorted[j]].Location
+		})
+	case BFSOrder:
+		visited := make([]bool, len(g.Nodes))
+		for root := range g.Nodes {
+			if visited[root] {
+				continue
+			}
+			visited[root] = true
+			// sorted is the queue of the search, the nodes are appended as
+			// they are found.
+			sorted = append(sorted, int32(root))
+			for next := len(sorted) - 1; next < len(sorted); next++ {
+				id := sorted[next]
+				for _, edges := range [2][]Edge{g.Outgoing(id), g.Incoming(id)} {
+					for _, e := range edges {
+						if !visited[e.ID] {
+							visited[e.ID] = true
+							sorted = append(sorted, e.ID)
+						}
+					}
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("gograph: unknown order %d", order)
+	}
+	ids := make([]int32, len(g.Nodes))
+	for i, old := range sorted {
+		ids[old] = int32(i)
+	}
+	g.Renumber(ids)
+	return ids, nil
+}
+
+// Renumber gives new IDs to the nodes of the graph. ids holds the new ID of
+// each node, indexed by its current ID, or -1 to remove the node with its
+// edges. The new IDs must go from 0 to the number of nodes kept minus one.
+// The OSM references, attributes and elevations follow the nodes, the edges
+// keep their EdgeID and the edge index is rebuilt.
+func (g *Graph) Renumber(ids []int32) {
+	frozen := g.IsFrozen()
+	g.Thaw()
+
+	kept := 0
+	for _, id := range ids {
+		if id >= 0 {
+			kept++
+		}
+	}
+
+	nodes := make([]Node, kept)
+	outgoing := make(Relations, kept)
+	incoming := make(Relations, kept)
+	for old, id := range ids {
+		if id < 0 {
+			continue
+		}
+		n := g.Nodes[old]
+		n.ID = id
+		nodes[id] = n
+		outgoing[id] = renumberEdges(g.OutgoingEdges[old], ids)
+		incoming[id] = renumberEdges(g.IncomingEdges[old], ids)
+	}
+	g.Nodes, g.OutgoingEdges, g.IncomingEdges = nodes, outgoing, incoming
+
+	if g.OSM.Nodes != nil {
+		osmNodes := make([]int64, kept)
+		g.OSM.NodeIDs = make(map[int64]int32, kept)
+		for old, osmID := range g.OSM.Nodes {
+			if id := ids[old]; id >= 0 && osmID != 0 {
+				osmNodes[id] = osmID
+				g.OSM.NodeIDs[osmID] = id
+			}
+		}
+		g.OSM.Nodes = osmNodes
+	}
+	for id, ends := range g.EdgeEnds {
+		if ends == removedEdge {
+			continue
+		}
+		if key, ok := renumberKey(ends, ids); ok {
+			g.EdgeEnds[id] = key
+		} else {
+			g.EdgeEnds[id] = removedEdge
+		}
+	}
+	// the way references and attributes are kept by EdgeID, only the ones
+	// of the edges removed with their nodes are dropped.
+	if g.OSM.Ways != nil {
+		ways := make([]WayRef, len(g.OSM.Ways))
+		for id, ref := range g.OSM.Ways {
+			if _, ok := g.EdgeByID(EdgeID(id)); ok {
+				ways[id] = ref
+			}
+		}
+		g.OSM.Ways = ways
+	}
+	if g.Attributes != nil {
+		edges := make([]uint32, len(g.Attributes.Edges))
+		for id, i := range g.Attributes.Edges {
+			edges[id] = noAttributes
+			if _, ok := g.EdgeByID(EdgeID(id)); ok {
+				edges[id] = i
+			}
+		}
+		g.Attributes.Edges = edges
+	}
+	if g.Elevation != nil {
+		elevation := make([]float32, kept)
+		for old, e := range g.Elevation {
+			if id := ids[old]; id >= 0 {
+				elevation[id] = e
+			}
+		}
+		g.Elevation = elevation
+	}
+	if frozen {
+		g.Freeze()
+	}
+	g.EdgeIndex = g.BuildEdgeIndex()
+}
+
+// renumberEdges returns the edges to the nodes that are kept, with their new IDs.
+func renumberEdges(edges []Edge, ids []int32) []Edge {
+	result := make([]Edge, 0, len(edges))
+	for _, e := range edges {
+		if id := ids[e.ID]; id >= 0 {
+			e.ID = id
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func renumberKey(key EdgeKey, ids []int32) (EdgeKey, bool) {
+	from, to := ids[key.From], ids[key.To]
+	return EdgeKey{From: from, To: to}, from >= 0 && to >= 0
+}
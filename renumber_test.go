@@ -0,0 +1,42 @@
+package gograph
+
+import "testing"
+
+func TestGraph_Reorder(t *testing.T) {
+	for _, order := range []Order{HilbertOrder, BFSOrder} {
+		g := testGraph()
+		g.Nodes[3].Data = []uint64{42}
+		g.SetOSMNode(3, 300)
+		before := g.Dijkstra(ShortestPathCriteria{From: 0, To: 3})
+		locations := make([]uint64, len(g.Nodes))
+		for i, n := range g.Nodes {
+			locations[i] = n.Location
+		}
+		ids, err := g.Reorder(order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for old, id := range ids {
+			if g.Nodes[id].Location != locations[old] || g.Nodes[id].ID != id {
+				t.Fatalf("node %d was not moved to %d", old, id)
+			}
+		}
+		if order == HilbertOrder {
+			for i := 1; i < len(g.Nodes); i++ {
+				if g.Nodes[i-1].Location > g.Nodes[i].Location {
+					t.Fatalf("expected the nodes sorted by location")
+				}
+			}
+		}
+		if cost := g.Dijkstra(ShortestPathCriteria{From: ids[0], To: ids[3]}); cost != before {
+			t.Fatalf("expected cost %f, got %f", before, cost)
+		}
+		if id, _ := g.NodeByOSMID(300); id != ids[3] || g.Nodes[id].Data[0] != 42 {
+			t.Fatalf("expected the references of node 3 at %d", ids[3])
+		}
+	}
+	g := testGraph()
+	if _, err := g.Reorder(Order(-1)); err == nil || g.Nodes[3].ID != 3 {
+		t.Fatal("expected an error for an unknown order and the graph unchanged")
+	}
+}
@@ -1,10 +1,43 @@
 package gograph
 
+// Network is what a search needs from a graph. *Graph implements it, and so
+// can the graphs loaded by parts, like the tiles package.
+type Network interface {
+	// Outgoing returns the edges leaving a node.
+	Outgoing(id int32) []Edge
+	// Node returns a node by ID.
+	Node(id int32) Node
+}
+
+// ShortestPathCriteria configures a search. Edges of any class in Avoid are
+// not used, and the weight of the edges of a class in Penalties is
+// multiplied by its factor. When PolylinePrecision is set, the geometry of
+// the path is also encoded in Path.Polyline with that number of decimals, see
+// the polyline package.
 type ShortestPathCriteria struct {
-	From        int32
-	To          int32
-	MaxCost     float32
-	InitialCost float32
+	From              int32
+	To                int32
+	MaxCost           float32
+	InitialCost       float32
+	Avoid             EdgeClass
+	Penalties         map[EdgeClass]float32
+	PolylinePrecision int
+}
+
+// weight returns the weight of an edge for the search, false if it has to be avoided.
+func (s ShortestPathCriteria) weight(e Edge) (float32, bool) {
+	if e.Class&s.Avoid != 0 {
+		return 0, false
+	}
+	weight := e.Weight
+	if e.Class != 0 {
+		for class, factor := range s.Penalties {
+			if e.Class&class != 0 {
+				weight *= factor
+			}
+		}
+	}
+	return weight, true
 }
 
 type Distances map[int32]float32
@@ -0,0 +1,331 @@
+package gograph
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// A serialized graph starts with the magic "GOGRAPHG", the format version
+// uint32 and the flags uint32, all little-endian. The graph follows, gob
+// encoded and gzip compressed when the flag bit 0 is set, split in chunks
+// each prefixed by its length uint32. An empty chunk ends the graph and is
+// followed by the CRC-32 (IEEE) of the bytes of all the chunks.
+// The edge index is not stored, it is rebuilt when the graph is read.
+const (
+	serializeMagic   = "GOGRAPHG"
+	serializeVersion = 4
+	// serializeGzip is the flag of the compressed graphs.
+	serializeGzip = 1
+	chunkSize     = 1 << 16
+)
+
+var (
+	// ErrNotGraph is returned when reading something that is not a graph.
+	ErrNotGraph = errors.New("gograph: not a serialized graph")
+	// ErrVersion is returned when the graph was written in a format version
+	// this package can not read.
+	ErrVersion = errors.New("gograph: unsupported format version")
+	// ErrChecksum is returned when the graph read does not match its checksum.
+	ErrChecksum = errors.New("gograph: checksum mismatch, the graph is corrupted")
+	// ErrLegacyFormat is returned when reading a headerless gob file written
+	// by the first versions of Serialize. Those could not encode the edge
+	// index, so the files hold no graph and it has to be built again.
+	ErrLegacyFormat = errors.New("gograph: legacy gob file without a graph, build the graph again")
+)
+
+// WriteTo writes the graph to w, see ReadFrom to read it back.
+func (g Graph) WriteTo(w io.Writer) (int64, error) {
+	return g.write(w, false)
+}
+
+// WriteGzipTo works as WriteTo but compresses the graph with gzip.
+func (g Graph) WriteGzipTo(w io.Writer) (int64, error) {
+	return g.write(w, true)
+}
+
+func (g Graph) write(w io.Writer, compress bool) (int64, error) {
+	cw := &countingWriter{w: w}
+	header := make([]byte, len(serializeMagic)+8)
+	copy(header, serializeMagic)
+	binary.LittleEndian.PutUint32(header[8:], serializeVersion)
+	if compress {
+		binary.LittleEndian.PutUint32(header[12:], serializeGzip)
+	}
+	if _, err := cw.Write(header); err != nil {
+		return cw.n, err
+	}
+
+	chunks := &chunkWriter{w: cw, crc: crc32.NewIEEE()}
+	var payload io.Writer = chunks
+	var zw *gzip.Writer
+	if compress {
+		zw = gzip.NewWriter(chunks)
+		payload = zw
+	}
+	if err := gob.NewEncoder(payload).Encode(newGraphFile(g)); err != nil {
+		return cw.n, fmt.Errorf("gograph: encoding graph: %w", err)
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return cw.n, err
+		}
+	}
+	err := chunks.Close()
+	return cw.n, err
+}
+
+// ReadFrom replaces the graph by the one read from r, written by WriteTo,
+// WriteGzipTo or SerializeAs in the Binary format, and rebuilds the edge
+// index. It returns a descriptive error for the graphs written in an
+// unsupported version or corrupted, and ErrLegacyFormat for the files of the
+// first versions of Serialize.
+func (g *Graph) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	magic := make([]byte, len(serializeMagic))
+	if _, err := io.ReadFull(cr, magic); err != nil {
+		return cr.n, fmt.Errorf("%w: %v", ErrNotGraph, err)
+	}
+	switch string(magic) {
+	case binaryMagic:
+		data, err := io.ReadAll(cr)
+		if err != nil {
+			return cr.n, err
+		}
+		decoded, err := readBinary(append(magic, data...))
+		if err != nil {
+			return cr.n, err
+		}
+		*g = decoded
+		return cr.n, nil
+	case serializeMagic:
+	default:
+		if legacyGob(magic, cr) {
+			return cr.n, ErrLegacyFormat
+		}
+		return cr.n, ErrNotGraph
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(cr, header); err != nil {
+		return cr.n, fmt.Errorf("gograph: reading header: %w", err)
+	}
+	if v := binary.LittleEndian.Uint32(header); v != serializeVersion {
+		return cr.n, fmt.Errorf("%w %d, expected version %d", ErrVersion, v, serializeVersion)
+	}
+	flags := binary.LittleEndian.Uint32(header[4:])
+
+	chunks := &chunkReader{r: cr, crc: crc32.NewIEEE()}
+	decoded, err := decodeGob(chunks, flags&serializeGzip != 0)
+	// the checksum is checked even if the decoding failed, as a corrupted
+	// graph usually fails to decode.
+	if _, cerr := io.Copy(io.Discard, chunks); cerr != nil {
+		return cr.n, cerr
+	}
+	if err != nil {
+		return cr.n, err
+	}
+	decoded.EdgeIndex = decoded.BuildEdgeIndex()
+	*g = decoded
+	return cr.n, nil
+}
+
+// graphFile holds the fields of a graph that are gob encoded. The edge index
+// is left out, gob can not encode its nil children and it is rebuilt anyway.
+type graphFile struct {
+	Nodes         []Node
+	IncomingEdges Relations
+	OutgoingEdges Relations
+	OSM           OSMRefs
+	Attributes    *Attributes
+	Elevation     []float32
+	Frozen        *Frozen
+	EdgeEnds      []EdgeKey
+}
+
+func newGraphFile(g Graph) graphFile {
+	return graphFile{
+		Nodes:         g.Nodes,
+		IncomingEdges: g.IncomingEdges,
+		OutgoingEdges: g.OutgoingEdges,
+		OSM:           g.OSM,
+		Attributes:    g.Attributes,
+		Elevation:     g.Elevation,
+		Frozen:        g.Frozen,
+		EdgeEnds:      g.EdgeEnds,
+	}
+}
+
+func decodeGob(r io.Reader, compressed bool) (Graph, error) {
+	if compressed {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return Graph{}, fmt.Errorf("gograph: decompressing graph: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	f := graphFile{}
+	if err := gob.NewDecoder(r).Decode(&f); err != nil {
+		return Graph{}, fmt.Errorf("gograph: decoding graph: %w", err)
+	}
+	return Graph{
+		Nodes:         f.Nodes,
+		IncomingEdges: f.IncomingEdges,
+		OutgoingEdges: f.OutgoingEdges,
+		OSM:           f.OSM,
+		Attributes:    f.Attributes,
+		Elevation:     f.Elevation,
+		Frozen:        f.Frozen,
+		EdgeEnds:      f.EdgeEnds,
+	}, nil
+}
+
+// legacyGob reports whether a file starts as the headerless gob encoding of a
+// Graph, with its head already read, as the first versions of Serialize wrote.
+func legacyGob(head []byte, r io.Reader) bool {
+	rest := make([]byte, 56)
+	n, _ := io.ReadFull(r, rest)
+	head = append(head, rest[:n]...)
+	return bytes.Contains(head, []byte("\x05Graph")) && bytes.Contains(head, []byte("\x05Nodes"))
+}
+
+// ReadFile reads a graph file written by Serialize or SerializeAs.
+func ReadFile(filePath string) (Graph, error) {
+	g := Graph{}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return g, err
+	}
+	defer file.Close()
+	if _, err := g.ReadFrom(bufio.NewReader(file)); err != nil {
+		return Graph{}, fmt.Errorf("%w (%s)", err, filePath)
+	}
+	return g, nil
+}
+
+// chunkWriter splits what is written in chunks and computes their checksum.
+type chunkWriter struct {
+	w   io.Writer
+	buf []byte
+	crc hash.Hash32
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	c.buf = append(c.buf, p...)
+	for len(c.buf) >= chunkSize {
+		if err := c.flush(c.buf[:chunkSize]); err != nil {
+			return 0, err
+		}
+		c.buf = c.buf[:copy(c.buf, c.buf[chunkSize:])]
+	}
+	return len(p), nil
+}
+
+func (c *chunkWriter) flush(chunk []byte) error {
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(chunk)))
+	if _, err := c.w.Write(size); err != nil {
+		return err
+	}
+	c.crc.Write(chunk)
+	_, err := c.w.Write(chunk)
+	return err
+}
+
+// Close writes the last chunk, the empty one and the checksum.
+func (c *chunkWriter) Close() error {
+	if len(c.buf) > 0 {
+		if err := c.flush(c.buf); err != nil {
+			return err
+		}
+	}
+	trailer := make([]byte, 8)
+	binary.LittleEndian.PutUint32(trailer[4:], c.crc.Sum32())
+	_, err := c.w.Write(trailer)
+	return err
+}
+
+// chunkReader joins the chunks of a chunkWriter, checking their checksum.
+// Once the chunks end, or fail to be read, every read returns the same error.
+type chunkReader struct {
+	r    io.Reader
+	left uint32
+	crc  hash.Hash32
+	err  error
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	if c.left == 0 {
+		c.left, c.err = c.next()
+		if c.err != nil {
+			return 0, c.err
+		}
+	}
+	if uint32(len(p)) > c.left {
+		p = p[:c.left]
+	}
+	n, err := c.r.Read(p)
+	c.crc.Write(p[:n])
+	c.left -= uint32(n)
+	if err == io.EOF {
+		err = fmt.Errorf("gograph: truncated graph: %w", io.ErrUnexpectedEOF)
+	}
+	c.err = err
+	return n, err
+}
+
+// next reads the size of the next chunk, and the checksum after the last one.
+func (c *chunkReader) next() (uint32, error) {
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(c.r, b); err != nil {
+		return 0, fmt.Errorf("gograph: truncated graph: %w", err)
+	}
+	size := binary.LittleEndian.Uint32(b)
+	if size > chunkSize {
+		return 0, fmt.Errorf("%w: chunk of %d bytes", ErrChecksum, size)
+	}
+	if size > 0 {
+		return size, nil
+	}
+	if _, err := io.ReadFull(c.r, b); err != nil {
+		return 0, fmt.Errorf("gograph: truncated graph: %w", err)
+	}
+	if binary.LittleEndian.Uint32(b) != c.crc.Sum32() {
+		return 0, ErrChecksum
+	}
+	return 0, io.EOF
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
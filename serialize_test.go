@@ -0,0 +1,99 @@
+package gograph
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"github.com/JesseleDuran/gograph/nearest_edge"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGraph_WriteTo(t *testing.T) {
+	g := testGraph()
+	g.Attributes = NewAttributes()
+	g.Attributes.Set(0, EdgeAttributes{Name: "Calle 26"})
+	for _, compress := range []bool{false, true} {
+		var b bytes.Buffer
+		write := g.WriteTo
+		if compress {
+			write = g.WriteGzipTo
+		}
+		n, err := write(&b)
+		if err != nil || n != int64(b.Len()) {
+			t.Fatalf("unexpected write of %d bytes: %v", n, err)
+		}
+		data := append([]byte{}, b.Bytes()...)
+		r := Graph{}
+		if n, err := r.ReadFrom(&b); err != nil || n != int64(len(data)) {
+			t.Fatalf("unexpected read of %d bytes: %v", n, err)
+		}
+		if !reflect.DeepEqual(r.Nodes, g.Nodes) || r.Edges() != g.Edges() || !reflect.DeepEqual(r.OutgoingEdges[0], g.OutgoingEdges[0]) {
+			t.Fatal("expected the same graph")
+		}
+		if attr, _ := r.EdgeAttributes(0); attr.Name != "Calle 26" {
+			t.Fatalf("expected the attributes, got %v", attr)
+		}
+		if p := r.ShortestPath(ShortestPathCriteria{From: 0, To: 3}); p.Cost != 3 {
+			t.Fatalf("expected cost 3, got %f", p.Cost)
+		}
+
+		corrupted := append([]byte{}, data...)
+		corrupted[len(corrupted)-20] ^= 0xff
+		if _, err := r.ReadFrom(bytes.NewReader(corrupted)); !errors.Is(err, ErrChecksum) {
+			t.Fatalf("expected a checksum error, got %v", err)
+		}
+		if _, err := r.ReadFrom(bytes.NewReader(data[:len(data)-10])); err == nil {
+			t.Fatal("expected an error on a truncated graph")
+		}
+		data[8] = 9
+		if _, err := r.ReadFrom(bytes.NewReader(data)); !errors.Is(err, ErrVersion) {
+			t.Fatalf("expected a version error, got %v", err)
+		}
+	}
+	if _, err := (&Graph{}).ReadFrom(bytes.NewReader([]byte("not a graph"))); !errors.Is(err, ErrNotGraph) {
+		t.Fatalf("expected a format error, got %v", err)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	g := testGraph()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "graph.gob")
+	if err := g.Serialize(path); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := ReadFile(path); err != nil || !reflect.DeepEqual(r.Nodes, g.Nodes) {
+		t.Fatalf("unexpected graph: %v", err)
+	}
+
+	if _, err := ReadFile(filepath.Join(dir, "missing.gob")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected a missing file error, got %v", err)
+	}
+
+	// the first versions of Serialize gob encoded the graph as is, which
+	// fails on the edge index after writing the types.
+	type Edge struct {
+		ID     int32
+		Weight float32
+	}
+	type Graph struct {
+		Nodes         []Node
+		IncomingEdges [][]Edge
+		OutgoingEdges [][]Edge
+		EdgeIndex     nearest_edge.Node
+	}
+	var legacy bytes.Buffer
+	if err := gob.NewEncoder(&legacy).Encode(Graph{Nodes: g.Nodes, EdgeIndex: g.BuildEdgeIndex()}); err == nil {
+		t.Fatal("expected the legacy encoding to fail")
+	}
+	path = filepath.Join(dir, "legacy.gob")
+	if err := os.WriteFile(path, legacy.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFile(path); !errors.Is(err, ErrLegacyFormat) {
+		t.Fatalf("expected ErrLegacyFormat, got %v", err)
+	}
+}
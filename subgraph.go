@@ -0,0 +1,118 @@
+package gograph
+
+import (
+	"fmt"
+	"github.com/golang/geo/s2"
+)
+
+// Subgraph returns a new graph with the nodes of g inside the region and the
+// edges between them, see SubgraphNodes.
+func (g Graph) Subgraph(region s2.Region) (Graph, []int32) {
+	keep := make([]bool, len(g.Nodes))
+	for i, n := range g.Nodes {
+		keep[i] = region.ContainsPoint(s2.CellID(n.Location).Point())
+	}
+	return g.subgraph(keep)
+}
+
+// SubgraphNodes returns a new graph with the given nodes of g and the edges
+// between them. The nodes are numbered from 0 keeping their order in g, and
+// the second value returned holds the ID in g of each node of the subgraph.
+// The data, OSM references, attributes and elevations of the nodes and edges
+// kept are copied, the edges get new EdgeIDs and the edge index is rebuilt. g
+// is not changed. An error is returned if an ID is not a node of g.
+func (g Graph) SubgraphNodes(ids []int32) (Graph, []int32, error) {
+	keep := make([]bool, len(g.Nodes))
+	for _, id := range ids {
+		if id < 0 || int(id) >= len(g.Nodes) {
+			return Graph{}, nil, fmt.Errorf("gograph: node %d out of range", id)
+		}
+		keep[id] = true
+	}
+	sub, parent := g.subgraph(keep)
+	return sub, parent, nil
+}
+
+func (g Graph) subgraph(keep []bool) (Graph, []int32) {
+	ids := make([]int32, len(g.Nodes))
+	parent := make([]int32, 0)
+	for i, ok := range keep {
+		ids[i] = -1
+		if ok {
+			ids[i] = int32(len(parent))
+			parent = append(parent, int32(i))
+		}
+	}
+
+	// Renumber builds new nodes, relations and OSM references, the fields it
+	// changes in place are copied first.
+	sub := g
+	sub.EdgeEnds = append([]EdgeKey(nil), g.EdgeEnds...)
+	if g.Attributes != nil {
+		sub.Attributes = &Attributes{Values: g.Attributes.Values, Edges: g.Attributes.Edges}
+	}
+	sub.Renumber(ids)
+	sub.compactEdgeIDs()
+	for i := range sub.Nodes {
+		if sub.Nodes[i].Data != nil {
+			sub.Nodes[i].Data = append([]uint64(nil), sub.Nodes[i].Data...)
+		}
+	}
+
+	// only the attribute values of the edges kept are stored.
+	if g.Attributes != nil {
+		edges := sub.Attributes.Edges
+		sub.Attributes = NewAttributes()
+		for id, i := range edges {
+			if i != noAttributes {
+				sub.Attributes.Set(EdgeID(id), g.Attributes.Values[i])
+			}
+		}
+	}
+	return sub, parent
+}
+
+// compactEdgeIDs gives consecutive EdgeIDs to the edges, in their current
+// order, so the IDs of the removed edges are no longer stored. The OSM way
+// references and attributes follow the edges.
+func (g *Graph) compactEdgeIDs() {
+	ids := make([]EdgeID, len(g.EdgeEnds))
+	ends := make([]EdgeKey, 0)
+	for id, key := range g.EdgeEnds {
+		if key != removedEdge {
+			ids[id] = EdgeID(len(ends))
+			ends = append(ends, key)
+		}
+	}
+	g.EdgeEnds = ends
+	for i := range g.Nodes {
+		for _, edges := range [2][]Edge{g.Outgoing(int32(i)), g.Incoming(int32(i))} {
+			for j := range edges {
+				if int(edges[j].EdgeID) < len(ids) {
+					edges[j].EdgeID = ids[edges[j].EdgeID]
+				}
+			}
+		}
+	}
+	if g.OSM.Ways != nil {
+		ways := make([]WayRef, len(ends))
+		for id, ref := range g.OSM.Ways {
+			if ref.ID != 0 && id < len(ids) {
+				ways[ids[id]] = ref
+			}
+		}
+		g.OSM.Ways = ways
+	}
+	if g.Attributes != nil {
+		edges := make([]uint32, len(ends))
+		for id := range edges {
+			edges[id] = noAttributes
+		}
+		for id, i := range g.Attributes.Edges {
+			if i != noAttributes && id < len(ids) {
+				edges[ids[id]] = i
+			}
+		}
+		g.Attributes.Edges = edges
+	}
+}
@@ -0,0 +1,77 @@
+package gograph
+
+import (
+	"github.com/golang/geo/s2"
+	"testing"
+)
+
+func TestGraph_Subgraph(t *testing.T) {
+	g := testGraph()
+	g.SetOSMNode(2, 200)
+	g.Elevation = []float32{0, 1, 2, 3}
+	g.Attributes = NewAttributes()
+	g.Attributes.Set(0, EdgeAttributes{Name: "Calle 26"})
+	g.Attributes.Set(4, EdgeAttributes{Name: "Carrera 7"})
+
+	region := s2.RectFromLatLng(s2.LatLngFromDegrees(4.599, -74.0795)).
+		AddPoint(s2.LatLngFromDegrees(4.601, -74.0765))
+	sub, parent := g.Subgraph(region)
+	if len(sub.Nodes) != 3 || len(parent) != 3 || parent[0] != 1 || parent[2] != 3 {
+		t.Fatalf("unexpected nodes %v %v", sub.Nodes, parent)
+	}
+	if sub.Edges() != 6 || len(sub.EdgeEnds) != 3 {
+		t.Fatalf("expected 3 edges in both directions, got %d with %d IDs", sub.Edges(), len(sub.EdgeEnds))
+	}
+	for id := range sub.EdgeEnds {
+		ends, _ := sub.EdgeByID(EdgeID(id))
+		if e, ok := sub.EdgeBetween(ends.From, ends.To); !ok || e.EdgeID != EdgeID(id) {
+			t.Fatalf("unexpected edge %d %v", id, ends)
+		}
+	}
+	if cost := sub.Dijkstra(ShortestPathCriteria{From: 0, To: 2}); cost != 2 {
+		t.Fatalf("expected cost 2, got %f", cost)
+	}
+	if id, ok := sub.NodeByOSMID(200); !ok || id != 1 || sub.Elevation[2] != 3 {
+		t.Fatalf("unexpected OSM node %d or elevation %v", id, sub.Elevation)
+	}
+	e, _ := sub.EdgeBetween(1, 2)
+	if attr, ok := sub.EdgeAttributes(e.EdgeID); !ok || attr.Name != "Carrera 7" || len(sub.Attributes.Values) != 1 {
+		t.Fatalf("unexpected attributes %v", sub.Attributes)
+	}
+	if sub.ProjectCoordinate(Coordinate{Lat: 4.6, Lng: -74.0785}); len(sub.EdgeIndex.Segments)+len(sub.EdgeIndex.Children) == 0 {
+		t.Fatal("expected the edge index rebuilt")
+	}
+
+	if len(g.Nodes) != 4 || g.Edges() != 12 || g.Attributes.Len() != 2 {
+		t.Fatal("expected the graph unchanged")
+	}
+	for id := range g.EdgeEnds {
+		if _, ok := g.EdgeByID(EdgeID(id)); !ok {
+			t.Fatalf("expected the edge %d kept in the graph", id)
+		}
+	}
+}
+
+func TestGraph_SubgraphNodes(t *testing.T) {
+	g := testGraph()
+	g.Nodes[3].Data = []uint64{30}
+	g.Freeze()
+	sub, parent, err := g.SubgraphNodes([]int32{3, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sub.Nodes) != 2 || parent[0] != 0 || parent[1] != 3 || !sub.IsFrozen() {
+		t.Fatalf("unexpected subgraph %v %v", sub.Nodes, parent)
+	}
+	if e, ok := sub.EdgeBetween(0, 1); !ok || e.Weight != 5 || e.EdgeID != 0 || sub.Edges() != 2 {
+		t.Fatalf("unexpected edges %v", sub.Outgoing(0))
+	}
+	if sub.Nodes[1].Data[0] = 31; len(g.Outgoing(0)) != 2 || g.Nodes[3].Data[0] != 30 {
+		t.Fatal("expected the graph unchanged")
+	}
+	for _, ids := range [][]int32{{0, 4}, {-1}} {
+		if _, _, err := g.SubgraphNodes(ids); err == nil {
+			t.Fatalf("expected an error for the nodes %v", ids)
+		}
+	}
+}
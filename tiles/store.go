@@ -0,0 +1,229 @@
+package tiles
+
+import (
+	"container/list"
+	"fmt"
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/JesseleDuran/gograph/nearest_edge"
+	"github.com/golang/geo/s2"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Store is a tiled graph loaded on demand. It implements graph.Network, so
+// the searches run on it as on the whole graph. At most capacity tiles are
+// kept in memory, the least recently used are dropped first. It is safe for
+// concurrent use.
+type Store struct {
+	dir      string
+	level    int
+	nodes    int32
+	tiles    []tileRef
+	cells    map[uint64]int
+	capacity int
+
+	mu    sync.Mutex
+	lru   *list.List
+	cache map[int]*list.Element
+	loads int
+	err   error
+	// failed are the errors of the tiles that could not be loaded, which are
+	// not read again.
+	failed map[int]error
+}
+
+// cached is a tile in the LRU list.
+type cached struct {
+	pos  int
+	tile *tile
+}
+
+// Open opens the tiles written to dir by Write. A capacity of 0 or less keeps
+// all the tiles loaded.
+func Open(dir string, capacity int) (*Store, error) {
+	m := manifest{}
+	if err := readGob(filepath.Join(dir, indexFile), &m); err != nil {
+		return nil, err
+	}
+	if m.Version != version {
+		return nil, fmt.Errorf("tiles: unsupported version %d in %s, expected %d", m.Version, dir, version)
+	}
+	s := &Store{
+		dir:      dir,
+		level:    m.Level,
+		nodes:    m.Nodes,
+		tiles:    m.Tiles,
+		cells:    make(map[uint64]int, len(m.Tiles)),
+		capacity: capacity,
+		lru:      list.New(),
+		cache:    make(map[int]*list.Element),
+		failed:   make(map[int]error),
+	}
+	for i, t := range m.Tiles {
+		s.cells[t.Cell] = i
+	}
+	return s, nil
+}
+
+// Len returns the number of nodes of the graph.
+func (s *Store) Len() int {
+	return int(s.nodes)
+}
+
+// Outgoing returns the edges leaving a node, loading its tile if needed.
+func (s *Store) Outgoing(id int32) []graph.Edge {
+	t, _ := s.tileOf(id)
+	return t.outgoing(id)
+}
+
+// Incoming returns the edges entering a node, loading its tile if needed.
+func (s *Store) Incoming(id int32) []graph.Edge {
+	t, _ := s.tileOf(id)
+	if t == nil {
+		return nil
+	}
+	return t.Incoming.Of(id - t.First)
+}
+
+// Node returns a node, loading its tile if needed. The nodes of the tiles
+// that can not be loaded are returned as compressed, so the searches skip them.
+func (s *Store) Node(id int32) graph.Node {
+	t, _ := s.tileOf(id)
+	return t.node(id)
+}
+
+// Dijkstra runs graph.Dijkstra on the tiles. It returns the error of the
+// first tile the search could not load, the cost being then the one of a path
+// that avoids the tile, if any.
+func (s *Store) Dijkstra(c graph.ShortestPathCriteria) (float32, error) {
+	n := &search{Store: s}
+	cost := graph.Dijkstra(n, c)
+	return cost, n.err
+}
+
+// ShortestPath runs graph.ShortestPath on the tiles. It returns the error of
+// the first tile the search could not load, the path being then one that
+// avoids the tile, if any.
+func (s *Store) ShortestPath(c graph.ShortestPathCriteria) (graph.Path, error) {
+	n := &search{Store: s}
+	p := graph.ShortestPath(n, c)
+	return p, n.err
+}
+
+// search is the network of a single search on the store, which keeps the
+// first error loading the tiles it uses.
+type search struct {
+	*Store
+	err error
+}
+
+func (n *search) tileOf(id int32) *tile {
+	t, err := n.Store.tileOf(id)
+	if err != nil && n.err == nil {
+		n.err = err
+	}
+	return t
+}
+
+func (n *search) Outgoing(id int32) []graph.Edge {
+	return n.tileOf(id).outgoing(id)
+}
+
+func (n *search) Node(id int32) graph.Node {
+	return n.tileOf(id).node(id)
+}
+
+// ProjectCoordinate works as Graph.ProjectCoordinate, looking for the nearest
+// edge in the tile of the coordinate and its neighbors. It returns -1 when
+// there is no edge around.
+func (s *Store) ProjectCoordinate(c graph.Coordinate) (int32, float32) {
+	cell := s2.CellIDFromLatLng(s2.LatLngFromDegrees(c.Lat, c.Lng)).Parent(s.level)
+	var nearest nearest_edge.GeoNearestResult
+	found := false
+	for _, cell := range append(cell.AllNeighbors(s.level), cell) {
+		pos, ok := s.cells[uint64(cell)]
+		if !ok {
+			continue
+		}
+		t, _ := s.load(pos)
+		if t == nil || t.edgeIndex() == nil {
+			continue
+		}
+		result := t.edgeIndex().GeoQuery(c.Lat, c.Lng, []int32{})
+		if !found || result.Distance < nearest.Distance {
+			nearest, found = result, true
+		}
+	}
+	if !found {
+		return -1, 0
+	}
+	return graph.SnapToEdge(s, nearest)
+}
+
+// Loaded returns the number of tiles in memory.
+func (s *Store) Loaded() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// Loads returns the number of times a tile was read from disk.
+func (s *Store) Loads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loads
+}
+
+// Err returns the first error loading a tile. The nodes of the tiles that
+// failed to load have no edges, and the tiles are not read again.
+func (s *Store) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// tileOf returns the tile of a node, nil without error if the node is not in
+// the store.
+func (s *Store) tileOf(id int32) (*tile, error) {
+	if id < 0 || id >= s.nodes {
+		return nil, nil
+	}
+	pos := sort.Search(len(s.tiles), func(i int) bool {
+		return s.tiles[i].First+s.tiles[i].Count > id
+	})
+	if pos == len(s.tiles) {
+		return nil, nil
+	}
+	return s.load(pos)
+}
+
+// load returns a tile, reading it from disk if it is not in memory.
+func (s *Store) load(pos int) (*tile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.cache[pos]; ok {
+		s.lru.MoveToFront(e)
+		return e.Value.(*cached).tile, nil
+	}
+	if err, ok := s.failed[pos]; ok {
+		return nil, err
+	}
+	t := &tile{}
+	path := filepath.Join(s.dir, tileName(s2.CellID(s.tiles[pos].Cell)))
+	if err := readGob(path, t); err != nil {
+		s.failed[pos] = err
+		if s.err == nil {
+			s.err = err
+		}
+		return nil, err
+	}
+	s.loads++
+	s.cache[pos] = s.lru.PushFront(&cached{pos: pos, tile: t})
+	for s.capacity > 0 && s.lru.Len() > s.capacity {
+		last := s.lru.Back()
+		s.lru.Remove(last)
+		delete(s.cache, last.Value.(*cached).pos)
+	}
+	return t, nil
+}
@@ -0,0 +1,200 @@
+// Package tiles splits a graph in S2 cell tiles stored in a directory, and
+// loads them on demand during the searches so the whole graph does not have
+// to fit in memory.
+//
+// The nodes of a tile are the nodes whose location is inside its cell. The
+// graph has to be sorted in Hilbert order first, see Graph.Reorder, so the
+// nodes of a tile have consecutive IDs. The edges keep the global node IDs,
+// the edges leaving a tile link it with its neighbors.
+package tiles
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/JesseleDuran/gograph/nearest_edge"
+	"github.com/golang/geo/s2"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultLevel is the S2 level of the tiles, the cells are about 10 km wide.
+const DefaultLevel = 10
+
+// version is the version of the tiles format.
+const version = 1
+
+// indexFile is the name of the file that lists the tiles of a directory.
+const indexFile = "index.gob"
+
+// ErrNotOrdered is returned when writing a graph not sorted in Hilbert order.
+var ErrNotOrdered = errors.New("tiles: the nodes are not in Hilbert order, call Reorder first")
+
+// manifest lists the tiles written in a directory.
+type manifest struct {
+	Version int
+	Level   int
+	Nodes   int32
+	Tiles   []tileRef
+}
+
+// tileRef locates the nodes of a tile.
+type tileRef struct {
+	Cell  uint64
+	First int32
+	Count int32
+}
+
+// tile holds the nodes of a cell with their edges.
+type tile struct {
+	First    int32
+	Nodes    []graph.Node
+	Outgoing graph.CSR
+	Incoming graph.CSR
+	// Neighbors holds the location of the nodes of other tiles related by the
+	// edges of the tile, to index the edges that cross its border.
+	Neighbors map[int32]uint64
+
+	indexOnce sync.Once
+	index     *nearest_edge.Node
+}
+
+// Write splits the graph in tiles of the given S2 level and writes them to
+// dir, which is created if needed. The edge attributes, the OSM references
+// and the elevation are not written.
+func Write(g graph.Graph, dir string, level int) error {
+	for i := 1; i < len(g.Nodes); i++ {
+		if g.Nodes[i-1].Location > g.Nodes[i].Location {
+			return ErrNotOrdered
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	m := manifest{Version: version, Level: level, Nodes: int32(len(g.Nodes))}
+	for first := 0; first < len(g.Nodes); {
+		cell := s2.CellID(g.Nodes[first].Location).Parent(level)
+		end := first
+		for end < len(g.Nodes) && cell.Contains(s2.CellID(g.Nodes[end].Location)) {
+			end++
+		}
+		ref := tileRef{Cell: uint64(cell), First: int32(first), Count: int32(end - first)}
+		if err := writeTile(g, ref, filepath.Join(dir, tileName(cell))); err != nil {
+			return err
+		}
+		m.Tiles = append(m.Tiles, ref)
+		first = end
+	}
+	return writeGob(filepath.Join(dir, indexFile), m)
+}
+
+func writeTile(g graph.Graph, ref tileRef, path string) error {
+	t := tile{
+		First:     ref.First,
+		Nodes:     g.Nodes[ref.First : ref.First+ref.Count],
+		Neighbors: make(map[int32]uint64),
+	}
+	out := make(graph.Relations, ref.Count)
+	in := make(graph.Relations, ref.Count)
+	for i := range out {
+		id := ref.First + int32(i)
+		out[i], in[i] = g.Outgoing(id), g.Incoming(id)
+		for _, edges := range [2][]graph.Edge{out[i], in[i]} {
+			for _, e := range edges {
+				if e.ID < ref.First || e.ID >= ref.First+ref.Count {
+					t.Neighbors[e.ID] = g.Nodes[e.ID].Location
+				}
+			}
+		}
+	}
+	t.Outgoing, t.Incoming = graph.NewCSR(out), graph.NewCSR(in)
+	return writeGob(path, &t)
+}
+
+func tileName(cell s2.CellID) string {
+	return cell.ToToken() + ".tile"
+}
+
+func writeGob(path string, v interface{}) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(file).Encode(v)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("tiles: writing %s: %w", path, err)
+	}
+	return nil
+}
+
+func readGob(path string, v interface{}) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := gob.NewDecoder(file).Decode(v); err != nil {
+		return fmt.Errorf("tiles: reading %s: %w", path, err)
+	}
+	return nil
+}
+
+// outgoing returns the edges leaving a node of the tile, none if the tile is
+// nil.
+func (t *tile) outgoing(id int32) []graph.Edge {
+	if t == nil {
+		return nil
+	}
+	return t.Outgoing.Of(id - t.First)
+}
+
+// node returns a node of the tile. When the tile is nil the node is returned
+// as compressed, so the searches skip it.
+func (t *tile) node(id int32) graph.Node {
+	if t == nil {
+		return graph.Node{ID: id, Compressed: true}
+	}
+	return t.Nodes[id-t.First]
+}
+
+// location returns the location of a node of the tile or of its neighbors.
+func (t *tile) location(id int32) uint64 {
+	if i := id - t.First; i >= 0 && int(i) < len(t.Nodes) {
+		return t.Nodes[i].Location
+	}
+	return t.Neighbors[id]
+}
+
+// edgeIndex returns the index of the edges of the tile, built the first time
+// it is needed. It is nil when the tile has no edges.
+func (t *tile) edgeIndex() *nearest_edge.Node {
+	t.indexOnce.Do(func() {
+		segments := make(nearest_edge.GeoSegments, 0)
+		unique := make(map[graph.EdgeKey]bool)
+		for i := range t.Nodes {
+			from := t.First + int32(i)
+			for _, e := range t.Outgoing.Of(int32(i)) {
+				if unique[graph.EdgeKey{From: e.ID, To: from}] {
+					continue
+				}
+				unique[graph.EdgeKey{From: from, To: e.ID}] = true
+				A := s2.CellID(t.location(from)).LatLng()
+				B := s2.CellID(t.location(e.ID)).LatLng()
+				segments = append(segments, nearest_edge.GeoSegment{
+					A: nearest_edge.GeoPointFromCoords(A.Lat.Degrees(), A.Lng.Degrees(), from),
+					B: nearest_edge.GeoPointFromCoords(B.Lat.Degrees(), B.Lng.Degrees(), e.ID),
+				})
+			}
+		}
+		if len(segments) > 0 {
+			index := nearest_edge.FromGeoSegments(segments...)
+			t.index = &index
+		}
+	})
+	return t.index
+}
@@ -0,0 +1,115 @@
+package tiles
+
+import (
+	"errors"
+	graph "github.com/JesseleDuran/gograph"
+	"github.com/golang/geo/s2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// grid builds a bidirectional grid of size x size nodes about 500 meters apart.
+func grid(size int) graph.Graph {
+	g := graph.Graph{}
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			ll := s2.LatLngFromDegrees(4.6+float64(i)*0.0045, -74.08+float64(j)*0.0045)
+			g.AddNode(graph.Node{Location: uint64(s2.CellIDFromLatLng(ll)), Data: []uint64{uint64(i*size + j)}})
+		}
+	}
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			id := i*size + j
+			if j+1 < size {
+				g.RelateNodes(g.Nodes[id], g.Nodes[id+1], float32(1+i), graph.Bidirectional)
+			}
+			if i+1 < size {
+				g.RelateNodes(g.Nodes[id], g.Nodes[id+size], float32(1+j), graph.LeftToRight)
+			}
+		}
+	}
+	g.EdgeIndex = g.BuildEdgeIndex()
+	return g
+}
+
+func TestStore(t *testing.T) {
+	g := grid(6)
+	dir := t.TempDir()
+	if err := Write(g, dir, 14); !errors.Is(err, ErrNotOrdered) {
+		t.Fatalf("expected the graph to require Hilbert order, got %v", err)
+	}
+	if _, err := g.Reorder(graph.HilbertOrder); err != nil {
+		t.Fatal(err)
+	}
+	// the tiles sort the edges as the frozen graph does, which is the order
+	// the nodes are visited in.
+	g.Freeze()
+	if err := Write(g, dir, 14); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != len(g.Nodes) || len(s.tiles) < 4 {
+		t.Fatalf("expected several tiles, got %d", len(s.tiles))
+	}
+	for from := int32(0); from < int32(len(g.Nodes)); from += 5 {
+		for to := int32(0); to < int32(len(g.Nodes)); to += 7 {
+			c := graph.ShortestPathCriteria{From: from, To: to}
+			want := graph.ShortestPath(&g, c)
+			if got, err := s.ShortestPath(c); err != nil || !reflect.DeepEqual(want, got) {
+				t.Fatalf("unexpected path from %d to %d: %v, expected %v: %v", from, to, got, want, err)
+			}
+			if cost, err := s.Dijkstra(c); err != nil || cost != g.Dijkstra(c) {
+				t.Fatalf("unexpected cost from %d to %d: %f: %v", from, to, cost, err)
+			}
+		}
+	}
+	if s.Loaded() > 2 || s.Err() != nil {
+		t.Fatalf("expected at most 2 tiles loaded, got %d: %v", s.Loaded(), s.Err())
+	}
+	at := graph.Coordinate{Lat: 4.6101, Lng: -74.0712}
+	want, _ := g.ProjectCoordinate(at)
+	if id, _ := s.ProjectCoordinate(at); id != want {
+		t.Fatalf("expected to project on node %d, got %d", want, id)
+	}
+	if id, _ := s.ProjectCoordinate(graph.Coordinate{Lat: 40, Lng: 3}); id != -1 {
+		t.Fatalf("expected no projection far from the tiles, got %d", id)
+	}
+}
+
+func TestStore_MissingTile(t *testing.T) {
+	g := grid(6)
+	if _, err := g.Reorder(graph.HilbertOrder); err != nil {
+		t.Fatal(err)
+	}
+	g.Freeze()
+	dir := t.TempDir()
+	if err := Write(g, dir, 14); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the tile of the target is removed.
+	to := int32(len(g.Nodes) - 1)
+	missing := s.tiles[len(s.tiles)-1]
+	if err := os.Remove(filepath.Join(dir, tileName(s2.CellID(missing.Cell)))); err != nil {
+		t.Fatal(err)
+	}
+	c := graph.ShortestPathCriteria{From: 0, To: to}
+	if _, err := s.ShortestPath(c); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the missing tile in the result, got %v", err)
+	}
+	loads := s.Loads()
+	if _, err := s.Dijkstra(c); !errors.Is(err, os.ErrNotExist) || s.Loads() != loads {
+		t.Fatalf("expected the failed tile not to be read again, got %v", err)
+	}
+	if !errors.Is(s.Err(), os.ErrNotExist) {
+		t.Fatalf("expected the failure in Err, got %v", s.Err())
+	}
+}